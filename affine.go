@@ -0,0 +1,81 @@
+package main
+
+import "math"
+
+// affine is a 2D affine transformation which maps x,y to
+//
+//	x' = m[0]*x + m[1]*y + m[2]
+//	y' = m[3]*x + m[4]*y + m[5]
+type affine [6]float64
+
+var identity = affine{1, 0, 0, 0, 1, 0}
+
+func translation(dx, dy float64) affine {
+	return affine{1, 0, dx, 0, 1, dy}
+}
+
+func scaling(sx, sy float64) affine {
+	return affine{sx, 0, 0, 0, sy, 0}
+}
+
+// rotation rotates by the given angle in radians. Since y points down, a
+// positive angle turns clockwise on the screen.
+func rotation(angle float64) affine {
+	sin, cos := math.Sincos(angle)
+	return affine{cos, -sin, 0, sin, cos, 0}
+}
+
+// shear slants horizontally by the given angle in radians, leaving the
+// horizontal line at y in place. Since y points down, a positive angle leans
+// the tops of letters to the right, like an oblique style.
+func shear(angle, y float64) affine {
+	t := math.Tan(angle)
+	return affine{1, -t, t * y, 0, 1, 0}
+}
+
+// mirrorX flips left and right around the vertical line at x.
+func mirrorX(x float64) affine {
+	return affine{-1, 0, 2 * x, 0, 1, 0}
+}
+
+// mirrorY flips up and down around the horizontal line at y.
+func mirrorY(y float64) affine {
+	return affine{1, 0, 0, 0, -1, 2 * y}
+}
+
+// around returns m as applied with x,y as the origin, e.g. a rotation around
+// that point instead of around 0,0.
+func (m affine) around(x, y float64) affine {
+	return translation(-x, -y).then(m).then(translation(x, y))
+}
+
+func (m affine) apply(x, y float64) (float64, float64) {
+	return m[0]*x + m[1]*y + m[2], m[3]*x + m[4]*y + m[5]
+}
+
+// then returns the transformation that first applies m and then n.
+func (m affine) then(n affine) affine {
+	return affine{
+		n[0]*m[0] + n[1]*m[3], n[0]*m[1] + n[1]*m[4], n[0]*m[2] + n[1]*m[5] + n[2],
+		n[3]*m[0] + n[4]*m[3], n[3]*m[1] + n[4]*m[4], n[3]*m[2] + n[4]*m[5] + n[5],
+	}
+}
+
+// transform applies m to the stroke. This is exact since lines and quadratic
+// curves are still the same lines and curves when their control points are
+// transformed.
+func (s *stroke) transform(m affine) {
+	s.x1, s.y1 = m.apply(s.x1, s.y1)
+	s.x2, s.y2 = m.apply(s.x2, s.y2)
+	s.x3, s.y3 = m.apply(s.x3, s.y3)
+}
+
+// transformed returns a transformed copy of the strokes.
+func (shape strokes) transformed(m affine) strokes {
+	out := make(strokes, len(shape))
+	for i := range shape {
+		out[i] = shape[i]
+		out[i].transform(m)
+	}
+	return out
+}
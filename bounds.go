@@ -0,0 +1,93 @@
+package main
+
+import "math"
+
+// bounds is an axis-aligned rectangle in canvas coordinates.
+type bounds struct {
+	minX, minY float64
+	maxX, maxY float64
+}
+
+// emptyBounds contains nothing. Adding any point to it gives the bounds of
+// just that point.
+var emptyBounds = bounds{
+	minX: math.Inf(1), minY: math.Inf(1),
+	maxX: math.Inf(-1), maxY: math.Inf(-1),
+}
+
+func (b bounds) empty() bool {
+	return b.minX > b.maxX || b.minY > b.maxY
+}
+
+func (b bounds) add(x, y float64) bounds {
+	return bounds{
+		minX: math.Min(b.minX, x), minY: math.Min(b.minY, y),
+		maxX: math.Max(b.maxX, x), maxY: math.Max(b.maxY, y),
+	}
+}
+
+func (b bounds) union(c bounds) bounds {
+	if c.empty() {
+		return b
+	}
+	return b.add(c.minX, c.minY).add(c.maxX, c.maxY)
+}
+
+// inflate grows the bounds by d on all sides.
+func (b bounds) inflate(d float64) bounds {
+	if b.empty() {
+		return b
+	}
+	return bounds{minX: b.minX - d, minY: b.minY - d, maxX: b.maxX + d, maxY: b.maxY + d}
+}
+
+func (b bounds) width() float64  { return b.maxX - b.minX }
+func (b bounds) height() float64 { return b.maxY - b.minY }
+
+// bounds returns the smallest rectangle that contains the stroke. Unlike the
+// control points of a curve, which usually lie outside of it, this is exact.
+func (s *stroke) bounds() bounds {
+	b := emptyBounds.add(s.x1, s.y1)
+	switch s.typ {
+	case dot:
+	case line:
+		b = b.add(s.x2, s.y2)
+	case curve:
+		b = b.add(s.x3, s.y3)
+		// The curve is farthest out where its derivative is 0 in x or y,
+		// i.e. where 2(1-t)(p2-p1) + 2t(p3-p2) = 0.
+		extremum := func(p1, p2, p3 float64) (float64, bool) {
+			d := p1 - 2*p2 + p3
+			if d == 0 {
+				return 0, false
+			}
+			t := (p1 - p2) / d
+			return t, t > 0 && t < 1
+		}
+		at := func(t float64) (float64, float64) {
+			u := 1 - t
+			return u*u*s.x1 + 2*u*t*s.x2 + t*t*s.x3,
+				u*u*s.y1 + 2*u*t*s.y2 + t*t*s.y3
+		}
+		if t, ok := extremum(s.x1, s.x2, s.x3); ok {
+			b = b.add(at(t))
+		}
+		if t, ok := extremum(s.y1, s.y2, s.y3); ok {
+			b = b.add(at(t))
+		}
+	default:
+		panic("unknown stroke type")
+	}
+	return b
+}
+
+// bounds returns the rectangle around all strokes. To get the area that is
+// actually inked, inflate it by half the pen width. The bounds are empty if
+// there are no strokes.
+func (shape strokes) bounds() bounds {
+	b := emptyBounds
+	for i := range shape {
+		b = b.union(shape[i].bounds())
+	}
+	return b
+}
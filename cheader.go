@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strings"
+)
+
+type cHeaderOptions struct {
+	// name prefixes every identifier in the header, e.g. "font" results in
+	// font_codepoints, font_draw_glyph, FONT_SCALE and so on.
+	name string
+	// bits is the size of a quantized coordinate, either 8 or 16.
+	bits int
+	// progmem puts the tables into flash on AVR targets, using PROGMEM and the
+	// pgm_read_* functions to access them. Other targets get plain const data.
+	progmem bool
+}
+
+// exportCHeader writes the font as a C header for drawing text on
+// microcontrollers. It contains the same tables as the file format described
+// above exportFile, only split into separate arrays and with coordinates
+// quantized to int8 or int16. Assuming the name "font", the header defines:
+//
+//	FONT_SCALE          coordinates are stored as round(x*FONT_SCALE), which is
+//	                    127 for int8 and 32767 for int16 coordinates
+//	FONT_BASELINE       the base line's y coordinate, quantized like above
+//	FONT_GLYPH_COUNT    number of entries in the following three tables
+//	FONT_NOTDEF         code point of the glyph for missing characters
+//	font_codepoints     uint32 unicode characters, sorted for binary search
+//	font_offsets        index of the glyph's first stroke in font_strokes
+//	font_stroke_counts  uint16 number of strokes for the glyph
+//	font_strokes        6 coordinates per stroke: x1, y1, x2, y2, x3, y3
+//	                    with the same meaning as in exportFile, a dot has all
+//	                    points the same, a line has the last two points the same
+//
+// font_draw_glyph is a reference implementation that walks the strokes of a
+// character and passes them as straight lines to a callback.
+func exportCHeader(list letters, path string, opt cHeaderOptions) error {
+	if !isCIdentifier(opt.name) {
+		return errors.New("C header name must be a valid C identifier")
+	}
+	if opt.bits != 8 && opt.bits != 16 {
+		return errors.New("C header coordinates must have 8 or 16 bits")
+	}
+
+	// notdef is stored as 0xFFFFFFFF, so unlike in Go it sorts last
+	list = simplify(list)
+	sort.Slice(list, func(i, j int) bool {
+		return uint32(list[i].r) < uint32(list[j].r)
+	})
+
+	name := opt.name
+	macro := strings.ToUpper(name)
+	scale := 127
+	coordType, readCoord := "int8_t", "((int8_t)pgm_read_byte(p))"
+	if opt.bits == 16 {
+		scale = 32767
+		coordType, readCoord = "int16_t", "((int16_t)pgm_read_word(p))"
+	}
+	quantize := func(x float64) int {
+		return int(math.Floor(x*float64(scale) + 0.5))
+	}
+	// coordinates outside the canvas might not fit into the integer type
+	for _, l := range list {
+		for _, s := range l.shape {
+			for _, x := range []float64{s.x1, s.y1, s.x2, s.y2, s.x3, s.y3} {
+				if q := quantize(x); q > scale || q < -scale-1 {
+					return fmt.Errorf(
+						"letter %s: coordinate %g does not fit into %d bits, "+
+							"move it inside the canvas",
+						letterName(l.r), x, opt.bits,
+					)
+				}
+			}
+		}
+	}
+
+	strokeCount := 0
+	for _, l := range list {
+		strokeCount += len(l.shape)
+	}
+	offsetType, readOffset := "uint16_t", "pgm_read_word(p)"
+	if strokeCount > math.MaxUint16 {
+		offsetType, readOffset = "uint32_t", "pgm_read_dword(p)"
+	}
+
+	var buf bytes.Buffer
+	w := &buf
+	p := func(format string, a ...interface{}) {
+		fmt.Fprintf(w, format, a...)
+	}
+
+	p("/* Generated by the stroke font editor. */\n\n")
+	p("#ifndef %s_H\n#define %s_H\n\n", macro, macro)
+	p("#include <stdint.h>\n\n")
+
+	if opt.progmem {
+		p("#if defined(__AVR__)\n")
+		p("#include <avr/pgmspace.h>\n")
+		p("#define %s_MEM PROGMEM\n", macro)
+		p("#define %s_READ_U32(p) pgm_read_dword(p)\n", macro)
+		p("#define %s_READ_U16(p) pgm_read_word(p)\n", macro)
+		p("#define %s_READ_OFFSET(p) %s\n", macro, readOffset)
+		p("#define %s_READ_COORD(p) %s\n", macro, readCoord)
+		p("#else\n")
+	}
+	p("#define %s_MEM\n", macro)
+	p("#define %s_READ_U32(p) (*(p))\n", macro)
+	p("#define %s_READ_U16(p) (*(p))\n", macro)
+	p("#define %s_READ_OFFSET(p) (*(p))\n", macro)
+	p("#define %s_READ_COORD(p) (*(p))\n", macro)
+	if opt.progmem {
+		p("#endif\n")
+	}
+	p("\n")
+
+	p("#define %s_SCALE %d\n", macro, scale)
+	p("#define %s_BASELINE %d\n", macro, quantize(baseLine))
+	p("#define %s_GLYPH_COUNT %d\n", macro, len(list))
+	p("#define %s_NOTDEF 0xFFFFFFFFu\n", macro)
+	p("#define %s_STROKE_COUNT %d\n\n", macro, strokeCount)
+
+	// Empty arrays are not valid C, so every table gets at least one entry.
+	// The glyph count makes sure these are never read.
+	p("static const uint32_t %s_codepoints[] %s_MEM = {\n", name, macro)
+	for _, l := range list {
+		p("\t0x%04X, /* %s */\n", uint32(l.r), letterName(l.r))
+	}
+	if len(list) == 0 {
+		p("\t0\n")
+	}
+	p("};\n\n")
+
+	p("static const %s %s_offsets[] %s_MEM = {\n", offsetType, name, macro)
+	offset := 0
+	for _, l := range list {
+		p("\t%d,\n", offset)
+		offset += len(l.shape)
+	}
+	if len(list) == 0 {
+		p("\t0\n")
+	}
+	p("};\n\n")
+
+	p("static const uint16_t %s_stroke_counts[] %s_MEM = {\n", name, macro)
+	for _, l := range list {
+		p("\t%d,\n", len(l.shape))
+	}
+	if len(list) == 0 {
+		p("\t0\n")
+	}
+	p("};\n\n")
+
+	p("static const %s %s_strokes[] %s_MEM = {\n", coordType, name, macro)
+	for _, l := range list {
+		p("\t/* %s */\n", letterName(l.r))
+		for _, s := range l.shape {
+			x1, y1, x2, y2, x3, y3 := s.x1, s.y1, s.x2, s.y2, s.x3, s.y3
+			switch s.typ {
+			case dot:
+				x2, y2, x3, y3 = x1, y1, x1, y1
+			case line:
+				x3, y3 = x2, y2
+			case curve:
+			default:
+				panic("unknown stroke type")
+			}
+			p("\t%d, %d, %d, %d, %d, %d,\n",
+				quantize(x1), quantize(y1),
+				quantize(x2), quantize(y2),
+				quantize(x3), quantize(y3),
+			)
+		}
+	}
+	if strokeCount == 0 {
+		p("\t0\n")
+	}
+	p("};\n\n")
+
+	p(strings.NewReplacer("font_", name+"_", "FONT_", macro+"_").Replace(
+		cHeaderWalker,
+	))
+	p("\n#endif\n")
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0666)
+}
+
+// cHeaderWalker is the C code that exportCHeader appends to the tables. The
+// prefixes font_ and FONT_ are replaced by the header's name.
+const cHeaderWalker = `#ifndef FONT_CURVE_STEPS
+#define FONT_CURVE_STEPS 8
+#endif
+
+/* font_line_func is called by font_draw_glyph for every straight piece of a
+   glyph, in screen coordinates. Dots are passed as lines of length zero. */
+typedef void (*font_line_func)(int x1, int y1, int x2, int y2, void *user);
+
+/* font_find_glyph returns the index of c in font_codepoints or -1 if the font
+   does not contain c. */
+static int font_find_glyph(uint32_t c) {
+	int lo = 0, hi = FONT_GLYPH_COUNT - 1;
+	while (lo <= hi) {
+		int mid = (lo + hi) / 2;
+		uint32_t m = FONT_READ_U32(&font_codepoints[mid]);
+		if (m == c)
+			return mid;
+		if (m < c)
+			lo = mid + 1;
+		else
+			hi = mid - 1;
+	}
+	return -1;
+}
+
+/* font_draw_glyph draws character c into a square of size by size pixels
+   with its top-left corner at x,y. The base line is at
+   y + size * FONT_BASELINE / FONT_SCALE. Curves are split into
+   FONT_CURVE_STEPS straight lines. It returns 0 if c is not in the font, in
+   which case the glyph FONT_NOTDEF is drawn instead, if the font has it. */
+static int font_draw_glyph(uint32_t c, int x, int y, int size,
+		font_line_func line, void *user) {
+	long s, end, n = FONT_CURVE_STEPS;
+	int i = font_find_glyph(c), found = i >= 0;
+	if (!found)
+		i = font_find_glyph(FONT_NOTDEF);
+	if (i < 0)
+		return 0;
+	s = FONT_READ_OFFSET(&font_offsets[i]);
+	end = s + FONT_READ_U16(&font_stroke_counts[i]);
+	for (; s < end; s++) {
+		long p[6];
+		int k;
+		for (k = 0; k < 6; k++)
+			p[k] = (long)FONT_READ_COORD(&font_strokes[6*s+k]) * size / FONT_SCALE;
+		if (p[2] == p[4] && p[3] == p[5]) {
+			/* a line from point 1 to point 2, or a dot if they are equal */
+			line(x+p[0], y+p[1], x+p[2], y+p[3], user);
+		} else {
+			/* a quadratic bezier curve from point 1 to point 3 with control
+			   point 2 */
+			long lastX = p[0], lastY = p[1], t;
+			for (t = 1; t <= n; t++) {
+				long u = n - t;
+				long curX = (u*u*p[0] + 2*u*t*p[2] + t*t*p[4]) / (n*n);
+				long curY = (u*u*p[1] + 2*u*t*p[3] + t*t*p[5]) / (n*n);
+				line(x+lastX, y+lastY, x+curX, y+curY, user);
+				lastX = curX;
+				lastY = curY;
+			}
+		}
+	}
+	return found;
+}
+`
+
+// cIdentifier turns s into a valid C identifier by replacing all other
+// characters with underscores.
+func cIdentifier(s string) string {
+	id := []rune(s)
+	for i, r := range id {
+		if !isCIdentifier(string(r)) && !(i > 0 && '0' <= r && r <= '9') {
+			id[i] = '_'
+		}
+	}
+	if len(id) == 0 {
+		return "font"
+	}
+	return string(id)
+}
+
+func isCIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		letter := r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z'
+		digit := '0' <= r && r <= '9'
+		if !letter && !(digit && i > 0) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,743 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// command is a sub-command that is run from the command line instead of
+// opening the editor window, e.g.
+//
+//	stroke_font_editor coverage -target ASCII font.stf
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"info": {
+		usage: "info font",
+		run:   infoCommand,
+	},
+	"validate": {
+		usage: "validate [-strict] font",
+		run:   validateCommand,
+	},
+	"convert": {
+		usage: "convert -to stf|svg|h [-name id] [-bits 8|16] [-progmem] [-o output] font",
+		run:   convertCommand,
+	},
+	"render": {
+		usage: "render -text text [-size px] [-pen px] [-rect] [-color rrggbb[aa]] [-background rrggbb[aa]] [-width w] [-align a] [-line-height h] [-fallback fonts] font output.png",
+		run:   renderCommand,
+	},
+	"export": {
+		usage: "export -text text [-size d] [-tolerance d] [-units name] [-dot r] [-pen w] [-rect] [-fill] [-width w] [-align a] [-line-height h] [-fallback fonts] font output.dxf|output.svg",
+		run:   exportCommand,
+	},
+	"merge": {
+		usage: "merge [-policy first|last|most|fail] -o output font font...",
+		run:   mergeCommand,
+	},
+	"diff": {
+		usage: "diff [-images dir] [-format png|svg] [-side] [-size s] [-pen w] old new",
+		run:   diffCommand,
+	},
+	"subset": {
+		usage: "subset [-text text] [-file path] [-ranges U+0030-U+0039,...] -o output font",
+		run:   subsetCommand,
+	},
+	"coverage": {
+		usage: "coverage [-target name] font",
+		run:   coverageCommand,
+	},
+	"metrics": {
+		usage: "metrics [-left d] [-right d] [-pen w] [-mono w] [-space w] font output",
+		run:   metricsCommand,
+	},
+	"transform": {
+		usage: "transform [-scale s|sx,sy] [-shear deg] [-rotate deg] [-mirror x|y|xy] [-move dx,dy] [-origin] [-runes text] font output",
+		run:   transformCommand,
+	},
+	"style": {
+		usage: "style [-shear deg] [-scale x] [-spacing d] [-name style] master output",
+		run:   styleCommand,
+	},
+}
+
+// runCommand runs the command line given in args, which start with the
+// command's name. It returns false if there is no such command.
+func runCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return false
+	}
+	if err := cmd.run(args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		fmt.Fprintln(os.Stderr, "usage:", cmd.usage)
+		os.Exit(1)
+	}
+	return true
+}
+
+// loadFont reads a font in one of the supported formats, judging by the file
+// extension.
+func loadFont(path string) (letters, fontInfo, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".svg":
+		return importSVGFont(path)
+	default:
+		return importFileWithInfo(path)
+	}
+}
+
+// saveFont writes the font in the format given by the file extension.
+func saveFont(list letters, info fontInfo, path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".svg":
+		return exportSVGFont(list, info, path)
+	default:
+		return exportFileWithInfo(list, info, path)
+	}
+}
+
+func coverageCommand(args []string) error {
+	flags := flag.NewFlagSet("coverage", flag.ContinueOnError)
+	var names []string
+	for _, set := range characterSets {
+		names = append(names, set.name)
+	}
+	sort.Strings(names)
+	target := flags.String(
+		"target", "ASCII",
+		"character set to check against: "+strings.Join(names, ", "),
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("exactly one font file expected")
+	}
+	set, ok := findCharacterSet(*target)
+	if !ok {
+		return fmt.Errorf("unknown character set %q", *target)
+	}
+	list, _, err := loadFont(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Print(coverage(list, set))
+	return nil
+}
+
+func metricsCommand(args []string) error {
+	flags := flag.NewFlagSet("metrics", flag.ContinueOnError)
+	left := flags.Float64("left", defaultSpacing.left, "left side bearing")
+	right := flags.Float64("right", defaultSpacing.right, "right side bearing")
+	pen := flags.Float64("pen", defaultSpacing.penWidth, "pen width")
+	mono := flags.Float64("mono", defaultSpacing.monospace, "advance of all letters if not 0")
+	space := flags.Float64("space", defaultSpacing.space, "advance of white space")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("input and output font files expected")
+	}
+	list, info, err := loadFont(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	return saveFont(autoMetrics(list, spacingRule{
+		left:      *left,
+		right:     *right,
+		penWidth:  *pen,
+		monospace: *mono,
+		space:     *space,
+	}), info, flags.Arg(1))
+}
+
+func transformCommand(args []string) error {
+	flags := flag.NewFlagSet("transform", flag.ContinueOnError)
+	scale := flags.String("scale", "1", "scale factor, for both axes or as x,y")
+	shearAngle := flags.Float64("shear", 0, "oblique angle in degrees, positive leans right")
+	rotate := flags.Float64("rotate", 0, "rotation in degrees, positive is clockwise")
+	mirror := flags.String("mirror", "", "x flips left and right, y flips up and down")
+	move := flags.String("move", "0,0", "translation as dx,dy, y points down")
+	origin := flags.Bool("origin", false, "transform around the left end of the base line instead of each letter's center")
+	runes := flags.String("runes", "", "letters to transform, all if empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("input and output font files expected")
+	}
+
+	t := glyphTransform{
+		shear:        *shearAngle * math.Pi / 180,
+		rotate:       *rotate * math.Pi / 180,
+		mirrorX:      strings.Contains(*mirror, "x"),
+		mirrorY:      strings.Contains(*mirror, "y"),
+		aroundOrigin: *origin,
+	}
+	if strings.Trim(*mirror, "xy") != "" {
+		return fmt.Errorf("invalid mirror %q", *mirror)
+	}
+	var err error
+	if t.scaleX, t.scaleY, err = parsePair(*scale); err != nil {
+		return fmt.Errorf("invalid scale: %v", err)
+	}
+	if t.dx, t.dy, err = parsePair(*move); err != nil {
+		return fmt.Errorf("invalid move: %v", err)
+	}
+	var only map[rune]bool
+	if *runes != "" {
+		only = make(map[rune]bool)
+		for _, r := range *runes {
+			only[r] = true
+		}
+	}
+
+	list, info, err := loadFont(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	return saveFont(transformLetters(list, t, only), info, flags.Arg(1))
+}
+
+// parsePair parses two comma-separated numbers, or a single one that is used
+// for both.
+func parsePair(s string) (float64, float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) > 2 {
+		return 0, 0, errors.New("one or two numbers expected")
+	}
+	a, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(parts) == 1 {
+		return a, a, nil
+	}
+	b, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	return a, b, err
+}
+
+func styleCommand(args []string) error {
+	flags := flag.NewFlagSet("style", flag.ContinueOnError)
+	shearAngle := flags.Float64("shear", 0, "oblique angle in degrees, positive leans right")
+	scale := flags.Float64("scale", 1, "horizontal scale, < 1 condenses and > 1 extends")
+	spacing := flags.Float64("spacing", 0, "added to both side bearings of every letter")
+	name := flags.String("name", "", "style name, made up from the parameters if empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("master and output font files expected")
+	}
+	if *scale <= 0 {
+		return errors.New("scale must be positive")
+	}
+	v := styleVariant{
+		name:    *name,
+		shear:   *shearAngle * math.Pi / 180,
+		scaleX:  *scale,
+		spacing: *spacing,
+	}
+
+	master, info, err := loadFont(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if info.family == "" {
+		path := flags.Arg(0)
+		info.family = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	info.style = styleName(v)
+	return saveFont(deriveStyle(master, v), info, flags.Arg(1))
+}
+
+func infoCommand(args []string) error {
+	flags := flag.NewFlagSet("info", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("exactly one font file expected")
+	}
+	list, info, err := loadFont(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var glyphs, dots, lines, curves, advances int
+	hasNotdef := false
+	b := emptyBounds
+	for _, l := range list {
+		if !hasGlyph(l) {
+			continue
+		}
+		glyphs++
+		if l.advance != 0 {
+			advances++
+		}
+		if l.r == notdef {
+			hasNotdef = true
+		}
+		for _, s := range l.shape {
+			switch s.typ {
+			case dot:
+				dots++
+			case line:
+				lines++
+			case curve:
+				curves++
+			default:
+				panic("unknown stroke type")
+			}
+		}
+		b = b.union(l.shape.bounds())
+	}
+
+	fmt.Println("family:  ", info.family)
+	fmt.Println("style:   ", info.style)
+	fmt.Println("letters: ", glyphs)
+	fmt.Printf(
+		"strokes:  %d (%d dots, %d lines, %d curves)\n",
+		dots+lines+curves, dots, lines, curves,
+	)
+	fmt.Printf("advances: %d defined, %d estimated\n", advances, glyphs-advances)
+	fmt.Println("notdef:  ", hasNotdef)
+	if !b.empty() {
+		fmt.Printf("bounds:   x %g to %g, y %g to %g\n", b.minX, b.maxX, b.minY, b.maxY)
+	}
+	for _, block := range coverage(list, characterSet{}).blocks {
+		fmt.Printf("%s (%d)\n\t%s\n", block.block.name, len(block.runes), runeList(block.runes))
+	}
+	return nil
+}
+
+func validateCommand(args []string) error {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	strict := flags.Bool("strict", false, "fail on warnings, too")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("exactly one font file expected")
+	}
+	list, _, err := loadFont(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, p := range validateFont(list) {
+		fmt.Println(p)
+		if p.fatal || *strict {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d problems found", failed)
+	}
+	return nil
+}
+
+func convertCommand(args []string) error {
+	flags := flag.NewFlagSet("convert", flag.ContinueOnError)
+	to := flags.String("to", "", "output format: stf, svg or h (C header)")
+	name := flags.String("name", "font", "C header: prefix of all identifiers")
+	bits := flags.Int("bits", 16, "C header: bits per coordinate, 8 or 16")
+	progmem := flags.Bool("progmem", false, "C header: put the tables into flash on AVR targets")
+	output := flags.String("o", "", "output file, defaults to the input with the format's extension")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("exactly one font file expected")
+	}
+	input := flags.Arg(0)
+	if *output == "" {
+		*output = strings.TrimSuffix(input, filepath.Ext(input)) + "." + *to
+	}
+	list, info, err := loadFont(input)
+	if err != nil {
+		return err
+	}
+	switch *to {
+	case "stf":
+		return exportFileWithInfo(list, info, *output)
+	case "svg":
+		return exportSVGFont(list, info, *output)
+	case "h":
+		return exportCHeader(list, *output, cHeaderOptions{
+			name:    *name,
+			bits:    *bits,
+			progmem: *progmem,
+		})
+	default:
+		return fmt.Errorf("unknown output format %q", *to)
+	}
+}
+
+// textFlags are the flags of all commands that set text.
+type textFlags struct {
+	text       *string
+	size       *float64
+	width      *float64
+	lineHeight *float64
+	align      *string
+	fallback   *string
+}
+
+func addTextFlags(flags *flag.FlagSet, size float64) textFlags {
+	return textFlags{
+		text:       flags.String("text", "", "the text, lines are separated by line breaks"),
+		size:       flags.Float64("size", size, "height of a letter's canvas"),
+		width:      flags.Float64("width", 0, "wrap lines to this width, 0 means no wrapping"),
+		lineHeight: flags.Float64("line-height", 1, "distance of base lines in letter heights"),
+		align:      flags.String("align", "left", "left, center, right or justify"),
+		fallback:   flags.String("fallback", "", "comma-separated fonts for letters that are missing from the font"),
+	}
+}
+
+// options checks the flags and loads the font with its fallbacks.
+func (t textFlags) options(path string) (*font, layoutOptions, error) {
+	opt := layoutOptions{size: *t.size, lineHeight: *t.lineHeight}
+	if *t.text == "" {
+		return nil, opt, errors.New("no text given")
+	}
+	if *t.size <= 0 {
+		return nil, opt, errors.New("size must be positive")
+	}
+	switch *t.align {
+	case "left":
+		opt.align = alignLeft
+	case "center":
+		opt.align = alignCenter
+	case "right":
+		opt.align = alignRight
+	case "justify":
+		opt.align = alignJustify
+	default:
+		return nil, opt, fmt.Errorf("unknown alignment %q", *t.align)
+	}
+
+	paths := []string{path}
+	if *t.fallback != "" {
+		paths = append(paths, strings.Split(*t.fallback, ",")...)
+	}
+	var first, last *font
+	for _, path := range paths {
+		list, _, err := loadFont(path)
+		if err != nil {
+			return nil, opt, err
+		}
+		f := newFont(list)
+		if first == nil {
+			first = f
+		} else {
+			last.fallback = f
+		}
+		last = f
+	}
+	return first, opt, nil
+}
+
+func renderCommand(args []string) error {
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	text := addTextFlags(flags, 64)
+	penWidth := flags.Float64("pen", 4, "pen width in pixels")
+	rect := flags.Bool("rect", false, "use a square pen instead of a round one")
+	fg := flags.String("color", "000000", "text color")
+	bg := flags.String("background", "", "background color, transparent if empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("font and output file expected")
+	}
+	f, layout, err := text.options(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	opt := renderOptions{penWidth: *penWidth, pen: circular}
+	if *rect {
+		opt.pen = rectangular
+	}
+	if opt.color, err = parseColor(*fg); err != nil {
+		return err
+	}
+
+	// the image contains the text's box and all ink, which might go beyond it
+	box := layoutBox{w: *text.width}
+	placed := layoutText(f, *text.text, box, layout)
+	m := measureText(f, *text.text, box, layout)
+	b := emptyBounds.add(m.x, m.y).add(m.x+m.w, m.y+m.h)
+	for _, l := range placed {
+		ink := l.shape.bounds()
+		b = b.add(l.x+ink.minX*l.size, l.y+ink.minY*l.size)
+		b = b.add(l.x+ink.maxX*l.size, l.y+ink.maxY*l.size)
+	}
+	b = b.inflate(*penWidth/2 + 1)
+	img := image.NewRGBA(image.Rect(
+		int(math.Floor(b.minX)), int(math.Floor(b.minY)),
+		int(math.Ceil(b.maxX)), int(math.Ceil(b.maxY)),
+	))
+	if *bg != "" {
+		c, err := parseColor(*bg)
+		if err != nil {
+			return err
+		}
+		draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	}
+	renderText(img, f, *text.text, box, layout, opt)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(flags.Arg(1), buf.Bytes(), 0666)
+}
+
+// parseColor reads a hexadecimal color with optional alpha, e.g. ff8000 or
+// ff800080.
+func parseColor(s string) (color.Color, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 8 || err != nil {
+		return nil, fmt.Errorf("invalid color %q", s)
+	}
+	return color.NRGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}, nil
+}
+
+func exportCommand(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	text := addTextFlags(flags, 10)
+	tolerance := flags.Float64("tolerance", 0.05, "maximum distance of curves to the lines replacing them")
+	units := flags.String("units", "mm", "DXF: units of all lengths, none, in, ft, mm, cm or m, the file is in mm")
+	dot := flags.Float64("dot", 0, "DXF: write dots as circles of this radius instead of points")
+	penWidth := flags.Float64("pen", 0.5, "SVG: stroke width")
+	rect := flags.Bool("rect", false, "SVG: square line ends instead of round ones")
+	fill := flags.Bool("fill", false, "SVG: write the outlines of the inked areas, filled, instead of the strokes")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("font and output file expected")
+	}
+	f, layout, err := text.options(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	output := flags.Arg(1)
+	switch strings.ToLower(filepath.Ext(output)) {
+	case ".dxf":
+		unit, ok := map[string]dxfUnit{
+			"none": dxfUnitless,
+			"in":   dxfInches,
+			"ft":   dxfFeet,
+			"mm":   dxfMillimeter,
+			"cm":   dxfCentimeter,
+			"m":    dxfMeter,
+		}[*units]
+		if !ok {
+			return fmt.Errorf("unknown units %q", *units)
+		}
+		// DXF's y axis points up, see writeDXF
+		return exportDXFText(
+			f, *text.text,
+			layoutBox{y: -baseLine * layout.size, w: *text.width},
+			layout, output, dxfOptions{
+				units:     unit,
+				tolerance: *tolerance,
+				dotRadius: *dot,
+			},
+		)
+	case ".svg":
+		pen := circular
+		if *rect {
+			pen = rectangular
+		}
+		box := layoutBox{w: *text.width}
+		if *fill {
+			polygons := layoutOutlines(
+				layoutText(f, *text.text, box, layout),
+				outlineOptions{penWidth: *penWidth, pen: pen, tolerance: *tolerance},
+			)
+			return exportSVGOutlines(polygons, output)
+		}
+		paths := textPaths(f, *text.text, box, layout, pathOptions{tolerance: *tolerance})
+		return exportSVGPaths(paths, output, *penWidth, pen)
+	default:
+		return errors.New("output file must be .dxf or .svg")
+	}
+}
+
+func mergeCommand(args []string) error {
+	flags := flag.NewFlagSet("merge", flag.ContinueOnError)
+	policyName := flags.String(
+		"policy", keepFirst.String(),
+		"letter to keep if fonts define it differently: "+strings.Join(mergePolicyNames, ", "),
+	)
+	output := flags.String("o", "", "output file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 2 {
+		return errors.New("at least two font files expected")
+	}
+	if *output == "" {
+		return errors.New("no output file given")
+	}
+	policy, ok := findMergePolicy(*policyName)
+	if !ok {
+		return fmt.Errorf("unknown merge policy %q", *policyName)
+	}
+
+	var sources []letters
+	var info fontInfo
+	for _, path := range flags.Args() {
+		list, i, err := loadFont(path)
+		if err != nil {
+			return err
+		}
+		if len(sources) == 0 {
+			info = i
+		}
+		sources = append(sources, list)
+	}
+	merged, report, err := mergeFonts(flags.Args(), sources, policy)
+	if err != nil {
+		return err
+	}
+	fmt.Print(report)
+	return saveFont(merged, info, *output)
+}
+
+func diffCommand(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	images := flags.String("images", "", "directory to write an image of every changed letter to")
+	format := flags.String("format", "png", "image format, png or svg")
+	side := flags.Bool("side", false, "draw old and new letters side by side instead of on top of each other")
+	size := flags.Float64("size", 200, "height of a letter in the images")
+	penWidth := flags.Float64("pen", 3, "pen width in the images")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("old and new font files expected")
+	}
+	if *format != "png" && *format != "svg" {
+		return fmt.Errorf("unknown image format %q", *format)
+	}
+	before, _, err := loadFont(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	after, _, err := loadFont(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	d := diffFonts(before, after)
+	if d.empty() {
+		fmt.Println("no differences")
+		return nil
+	}
+	fmt.Print(d)
+	if *images != "" {
+		if err := os.MkdirAll(*images, 0777); err != nil {
+			return err
+		}
+		for _, g := range d.changed {
+			path := filepath.Join(*images, diffImageName(g.r)+"."+*format)
+			err := exportDiffImage(g, path, diffImageOptions{
+				size:       *size,
+				penWidth:   *penWidth,
+				sideBySide: *side,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func subsetCommand(args []string) error {
+	flags := flag.NewFlagSet("subset", flag.ContinueOnError)
+	text := flags.String("text", "", "keep the characters in this text")
+	file := flags.String("file", "", "keep the characters in this UTF-8 text file")
+	ranges := flags.String("ranges", "", "keep these code points, e.g. U+0030-U+0039,U+0041")
+	output := flags.String("o", "", "output file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("exactly one font file expected")
+	}
+	if *output == "" {
+		return errors.New("no output file given")
+	}
+
+	keep := make(runeSet)
+	keep.addText(*text)
+	if *file != "" {
+		data, err := ioutil.ReadFile(*file)
+		if err != nil {
+			return err
+		}
+		keep.addText(string(data))
+	}
+	if err := keep.addRanges(*ranges); err != nil {
+		return err
+	}
+	if len(keep) == 0 {
+		return errors.New("no characters to keep given")
+	}
+
+	list, info, err := loadFont(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	subset := subsetFont(list, keep)
+
+	present := make(map[rune]bool)
+	for _, l := range subset {
+		if hasGlyph(l) {
+			present[l.r] = true
+		}
+	}
+	var missing []rune
+	for r := range keep {
+		if !present[r] {
+			missing = append(missing, r)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i] < missing[j] })
+	fmt.Printf("kept %d of %d letters\n", len(present), len(list))
+	if len(missing) > 0 {
+		fmt.Printf("not in the font (%d): %s\n", len(missing), runeList(missing))
+	}
+	return saveFont(subset, info, *output)
+}
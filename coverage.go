@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"unicode"
+)
+
+// unicodeBlock is a named range of code points as defined by the Unicode
+// standard.
+type unicodeBlock struct {
+	name        string
+	first, last rune
+}
+
+// unicodeBlocks lists the blocks that are most likely to be drawn as stroke
+// fonts, sorted by their first code point. Runes outside these are reported
+// as being in otherBlock.
+var unicodeBlocks = []unicodeBlock{
+	{"Basic Latin", 0x0000, 0x007F},
+	{"Latin-1 Supplement", 0x0080, 0x00FF},
+	{"Latin Extended-A", 0x0100, 0x017F},
+	{"Latin Extended-B", 0x0180, 0x024F},
+	{"IPA Extensions", 0x0250, 0x02AF},
+	{"Spacing Modifier Letters", 0x02B0, 0x02FF},
+	{"Combining Diacritical Marks", 0x0300, 0x036F},
+	{"Greek and Coptic", 0x0370, 0x03FF},
+	{"Cyrillic", 0x0400, 0x04FF},
+	{"Cyrillic Supplement", 0x0500, 0x052F},
+	{"Armenian", 0x0530, 0x058F},
+	{"Hebrew", 0x0590, 0x05FF},
+	{"Arabic", 0x0600, 0x06FF},
+	{"Devanagari", 0x0900, 0x097F},
+	{"Thai", 0x0E00, 0x0E7F},
+	{"Georgian", 0x10A0, 0x10FF},
+	{"Latin Extended Additional", 0x1E00, 0x1EFF},
+	{"Greek Extended", 0x1F00, 0x1FFF},
+	{"General Punctuation", 0x2000, 0x206F},
+	{"Superscripts and Subscripts", 0x2070, 0x209F},
+	{"Currency Symbols", 0x20A0, 0x20CF},
+	{"Letterlike Symbols", 0x2100, 0x214F},
+	{"Number Forms", 0x2150, 0x218F},
+	{"Arrows", 0x2190, 0x21FF},
+	{"Mathematical Operators", 0x2200, 0x22FF},
+	{"Miscellaneous Technical", 0x2300, 0x23FF},
+	{"Enclosed Alphanumerics", 0x2460, 0x24FF},
+	{"Box Drawing", 0x2500, 0x257F},
+	{"Block Elements", 0x2580, 0x259F},
+	{"Geometric Shapes", 0x25A0, 0x25FF},
+	{"Miscellaneous Symbols", 0x2600, 0x26FF},
+	{"Dingbats", 0x2700, 0x27BF},
+	{"Supplemental Arrows-A", 0x27F0, 0x27FF},
+	{"Latin Extended-C", 0x2C60, 0x2C7F},
+	{"CJK Symbols and Punctuation", 0x3000, 0x303F},
+	{"Hiragana", 0x3040, 0x309F},
+	{"Katakana", 0x30A0, 0x30FF},
+	{"CJK Unified Ideographs", 0x4E00, 0x9FFF},
+	{"Hangul Syllables", 0xAC00, 0xD7AF},
+	{"Private Use Area", 0xE000, 0xF8FF},
+	{"Alphabetic Presentation Forms", 0xFB00, 0xFB4F},
+	{"Halfwidth and Fullwidth Forms", 0xFF00, 0xFFEF},
+	{"Specials", 0xFFF0, 0xFFFF},
+}
+
+// otherBlock stands for all code points that are not in unicodeBlocks.
+var otherBlock = unicodeBlock{name: "Other", first: 0, last: unicode.MaxRune}
+
+func blockOf(r rune) unicodeBlock {
+	i := sort.Search(len(unicodeBlocks), func(i int) bool {
+		return unicodeBlocks[i].last >= r
+	})
+	if i < len(unicodeBlocks) && unicodeBlocks[i].first <= r {
+		return unicodeBlocks[i]
+	}
+	return otherBlock
+}
+
+// characterSet is a set of characters that a font is supposed to support.
+type characterSet struct {
+	name  string
+	runes []rune
+}
+
+// characterSets are the targets that coverage can be checked against.
+var characterSets = []characterSet{
+	{"ASCII", printableRunes(0x20, 0x7E)},
+	{"Latin-1", append(printableRunes(0x20, 0x7E), printableRunes(0xA0, 0xFF)...)},
+	{"Latin Extended-A", printableRunes(0x100, 0x17F)},
+	{"Greek", printableRunes(0x370, 0x3FF)},
+}
+
+func findCharacterSet(name string) (characterSet, bool) {
+	for _, set := range characterSets {
+		if set.name == name {
+			return set, true
+		}
+	}
+	return characterSet{}, false
+}
+
+// printableRunes returns all assigned, visible runes from first to last,
+// including the space.
+func printableRunes(first, last rune) []rune {
+	var runes []rune
+	for r := first; r <= last; r++ {
+		if unicode.IsPrint(r) {
+			runes = append(runes, r)
+		}
+	}
+	return runes
+}
+
+// hasGlyph tells if a letter is actually part of the font. Letters without
+// strokes are left in the font by the editor when it only shows them, but
+// they are still part of it if they have a width, like the space.
+func hasGlyph(l letter) bool {
+	return len(l.shape) > 0 || l.advance != 0
+}
+
+type coverageReport struct {
+	blocks  []blockCoverage
+	target  characterSet
+	covered int
+	missing []rune
+}
+
+type blockCoverage struct {
+	block unicodeBlock
+	runes []rune
+}
+
+func (c coverageReport) percentage() float64 {
+	if len(c.target.runes) == 0 {
+		return 100
+	}
+	return 100 * float64(c.covered) / float64(len(c.target.runes))
+}
+
+// coverage groups the letters in the list by Unicode block and checks which
+// runes of the target character set are missing. Runes in otherBlock are
+// grouped last. White space counts as covered even without a glyph, since
+// it is not drawn anyway, see font.glyph.
+func coverage(list letters, target characterSet) coverageReport {
+	report := coverageReport{target: target}
+
+	present := make(map[rune]bool)
+	for _, l := range list {
+		if hasGlyph(l) && l.r != notdef {
+			present[l.r] = true
+		}
+	}
+
+	runes := make([]rune, 0, len(present))
+	for r := range present {
+		runes = append(runes, r)
+	}
+	report.blocks = groupByBlock(runes)
+
+	for _, r := range target.runes {
+		if present[r] || unicode.IsSpace(r) {
+			report.covered++
+		} else {
+			report.missing = append(report.missing, r)
+		}
+	}
+
+	return report
+}
+
+// groupByBlock sorts the runes and groups them by Unicode block, in the order
+// of the blocks. Runes in otherBlock are grouped last.
+func groupByBlock(runes []rune) []blockCoverage {
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	var blocks []blockCoverage
+	other := blockCoverage{block: otherBlock}
+	for _, r := range runes {
+		b := blockOf(r)
+		if b == otherBlock {
+			other.runes = append(other.runes, r)
+			continue
+		}
+		n := len(blocks)
+		if n == 0 || blocks[n-1].block != b {
+			blocks = append(blocks, blockCoverage{block: b})
+			n++
+		}
+		blocks[n-1].runes = append(blocks[n-1].runes, r)
+	}
+	if len(other.runes) > 0 {
+		blocks = append(blocks, other)
+	}
+	return blocks
+}
+
+func (c coverageReport) String() string {
+	var s string
+	for _, b := range c.blocks {
+		s += fmt.Sprintf("%s (%d)\n\t%s\n", b.block.name, len(b.runes), runeList(b.runes))
+	}
+	s += fmt.Sprintf(
+		"%s: %d of %d (%.1f%%)\n",
+		c.target.name, c.covered, len(c.target.runes), c.percentage(),
+	)
+	if len(c.missing) > 0 {
+		s += "missing:\n"
+		for _, r := range c.missing {
+			s += fmt.Sprintf("\tU+%04X %q\n", r, r)
+		}
+	}
+	return s
+}
+
+// runeList writes the runes in a printable form, separated by spaces.
+func runeList(runes []rune) string {
+	var s string
+	for i, r := range runes {
+		if i > 0 {
+			s += " "
+		}
+		if unicode.IsGraphic(r) && r != ' ' {
+			s += string(r)
+		} else {
+			s += fmt.Sprintf("U+%04X", r)
+		}
+	}
+	return s
+}
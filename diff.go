@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// fontDiff lists the differences between an old and a new version of a font.
+type fontDiff struct {
+	added   []rune
+	removed []rune
+	changed []glyphDiff
+}
+
+// glyphDiff is the difference between the old and new version of a letter.
+type glyphDiff struct {
+	r        rune
+	old, new letter
+	// oldAdvance and newAdvance are the advances as they are used, estimated
+	// or not.
+	oldAdvance, newAdvance float64
+	strokes                []strokeDiff
+}
+
+type strokeDiffKind int
+
+const (
+	strokeMoved strokeDiffKind = iota
+	strokeAdded
+	strokeRemoved
+)
+
+// strokeDiff is a stroke that moved, was added or was removed.
+type strokeDiff struct {
+	kind strokeDiffKind
+	// old and new are the stroke's indices in the old and new letter, -1 for
+	// added or removed strokes.
+	old, new int
+	// moves has a delta for every point of a moved stroke.
+	moves [][2]float64
+}
+
+// diffFonts compares two fonts letter by letter. Strokes in changed letters
+// are matched regardless of their order and direction. Those that are not in
+// both letters are paired with the closest stroke of the same type and
+// reported as moved, all others as added or removed.
+func diffFonts(before, after letters) fontDiff {
+	var d fontDiff
+	fa, fb := newFont(before), newFont(after)
+	present := make(map[rune]bool)
+	for _, l := range append(append(letters(nil), before...), after...) {
+		if hasGlyph(l) {
+			present[l.r] = true
+		}
+	}
+	var runes []rune
+	for r := range present {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+
+	for _, r := range runes {
+		a, b := fa.glyphs[r], fb.glyphs[r]
+		if !hasGlyph(b) {
+			d.removed = append(d.removed, r)
+			continue
+		}
+		if !hasGlyph(a) {
+			d.added = append(d.added, r)
+			continue
+		}
+		g := glyphDiff{
+			r:          r,
+			old:        a,
+			new:        b,
+			oldAdvance: fa.advance(r),
+			newAdvance: fb.advance(r),
+			strokes:    diffStrokes(a.shape, b.shape),
+		}
+		if len(g.strokes) > 0 || math.Abs(g.oldAdvance-g.newAdvance) > 1e-6 {
+			d.changed = append(d.changed, g)
+		}
+	}
+	return d
+}
+
+func diffStrokes(before, after strokes) []strokeDiff {
+	var diffs []strokeDiff
+	oldUsed := make([]bool, len(before))
+	newUsed := make([]bool, len(after))
+
+	// strokes that are the same in both
+	for i := range before {
+		for j := range after {
+			if !newUsed[j] && sameStroke(before[i], after[j]) {
+				oldUsed[i], newUsed[j] = true, true
+				break
+			}
+		}
+	}
+
+	// pair the rest with the closest stroke of the same type, closest pairs
+	// first
+	type pair struct {
+		old, new int
+		moves    [][2]float64
+		distance float64
+	}
+	var pairs []pair
+	for i := range before {
+		for j := range after {
+			if oldUsed[i] || newUsed[j] || before[i].typ != after[j].typ {
+				continue
+			}
+			moves := strokeMoves(before[i], after[j])
+			distance := 0.0
+			for _, m := range moves {
+				distance += math.Hypot(m[0], m[1])
+			}
+			pairs = append(pairs, pair{old: i, new: j, moves: moves, distance: distance})
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].distance < pairs[j].distance
+	})
+	for _, p := range pairs {
+		if !oldUsed[p.old] && !newUsed[p.new] {
+			oldUsed[p.old], newUsed[p.new] = true, true
+			diffs = append(diffs, strokeDiff{
+				kind:  strokeMoved,
+				old:   p.old,
+				new:   p.new,
+				moves: p.moves,
+			})
+		}
+	}
+
+	for i := range before {
+		if !oldUsed[i] {
+			diffs = append(diffs, strokeDiff{kind: strokeRemoved, old: i, new: -1})
+		}
+	}
+	for j := range after {
+		if !newUsed[j] {
+			diffs = append(diffs, strokeDiff{kind: strokeAdded, old: -1, new: j})
+		}
+	}
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return diffOrder(diffs[i]) < diffOrder(diffs[j])
+	})
+	return diffs
+}
+
+// diffOrder sorts stroke differences by the old strokes' order, with added
+// strokes at the end.
+func diffOrder(d strokeDiff) int {
+	if d.old >= 0 {
+		return d.old
+	}
+	return math.MaxInt32 + d.new
+}
+
+// strokeMoves returns how far each point of the old stroke moved to get the
+// new one. The new stroke is reversed if that gives smaller moves.
+func strokeMoves(before, after stroke) [][2]float64 {
+	points := func(s stroke) [][2]float64 {
+		switch s.typ {
+		case dot:
+			return [][2]float64{{s.x1, s.y1}}
+		case line:
+			return [][2]float64{{s.x1, s.y1}, {s.x2, s.y2}}
+		case curve:
+			return [][2]float64{{s.x1, s.y1}, {s.x2, s.y2}, {s.x3, s.y3}}
+		default:
+			panic("unknown stroke type")
+		}
+	}
+	moves := func(a, b [][2]float64) ([][2]float64, float64) {
+		m := make([][2]float64, len(a))
+		sum := 0.0
+		for i := range a {
+			m[i] = [2]float64{b[i][0] - a[i][0], b[i][1] - a[i][1]}
+			sum += math.Hypot(m[i][0], m[i][1])
+		}
+		return m, sum
+	}
+	forward, f := moves(points(before), points(after))
+	after.flip()
+	backward, b := moves(points(before), points(after))
+	if b < f {
+		return backward
+	}
+	return forward
+}
+
+func (d fontDiff) empty() bool {
+	return len(d.added) == 0 && len(d.removed) == 0 && len(d.changed) == 0
+}
+
+func (d fontDiff) String() string {
+	var s string
+	names := func(runes []rune) string {
+		var s string
+		for i, r := range runes {
+			if i > 0 {
+				s += " "
+			}
+			s += letterName(r)
+		}
+		return s
+	}
+	if len(d.added) > 0 {
+		s += fmt.Sprintf("added (%d): %s\n", len(d.added), names(d.added))
+	}
+	if len(d.removed) > 0 {
+		s += fmt.Sprintf("removed (%d): %s\n", len(d.removed), names(d.removed))
+	}
+	for _, g := range d.changed {
+		s += fmt.Sprintf("changed %s:\n", letterName(g.r))
+		if math.Abs(g.oldAdvance-g.newAdvance) > 1e-6 {
+			s += fmt.Sprintf("\tadvance %.4g -> %.4g\n", g.oldAdvance, g.newAdvance)
+		}
+		for _, sd := range g.strokes {
+			switch sd.kind {
+			case strokeMoved:
+				s += fmt.Sprintf("\tstroke %d moved", sd.old)
+				for i, m := range sd.moves {
+					if m != [2]float64{} {
+						s += fmt.Sprintf(" p%d by %+.4g,%+.4g", i+1, m[0], m[1])
+					}
+				}
+				s += "\n"
+			case strokeAdded:
+				s += fmt.Sprintf("\tstroke %d added: %s\n", sd.new, strokeString(g.new.shape[sd.new]))
+			case strokeRemoved:
+				s += fmt.Sprintf("\tstroke %d removed: %s\n", sd.old, strokeString(g.old.shape[sd.old]))
+			default:
+				panic("unknown stroke difference")
+			}
+		}
+	}
+	return s
+}
+
+func strokeString(s stroke) string {
+	switch s.typ {
+	case dot:
+		return fmt.Sprintf("dot %.4g,%.4g", s.x1, s.y1)
+	case line:
+		return fmt.Sprintf("line %.4g,%.4g %.4g,%.4g", s.x1, s.y1, s.x2, s.y2)
+	case curve:
+		return fmt.Sprintf(
+			"curve %.4g,%.4g %.4g,%.4g %.4g,%.4g",
+			s.x1, s.y1, s.x2, s.y2, s.x3, s.y3,
+		)
+	default:
+		panic("unknown stroke type")
+	}
+}
+
+type diffImageOptions struct {
+	// size is the height of a letter's canvas in pixels for PNG and in user
+	// units for SVG images.
+	size     float64
+	penWidth float64
+	// sideBySide puts the old letter to the left of the new one instead of
+	// drawing them on top of each other.
+	sideBySide bool
+}
+
+// exportDiffImage draws the old version of a changed letter in red and the new
+// one in blue, both with their canvas' frame and base line. The image is a
+// PNG or SVG file, depending on the path's extension.
+func exportDiffImage(g glyphDiff, path string, opt diffImageOptions) error {
+	size := opt.size
+	canvases := 1
+	newX := 0.0
+	if opt.sideBySide {
+		canvases = 2
+		newX = size
+	}
+	placed := []placedLetter{
+		{r: g.r, size: size, shape: g.old.shape},
+		{r: g.r, x: newX, size: size, shape: g.new.shape},
+	}
+	var frame [][2][2]float64
+	for i := 0; i < canvases; i++ {
+		x := float64(i) * size
+		frame = append(frame,
+			[2][2]float64{{x, 0}, {x + size, 0}},
+			[2][2]float64{{x + size, 0}, {x + size, size}},
+			[2][2]float64{{x + size, size}, {x, size}},
+			[2][2]float64{{x, size}, {x, 0}},
+			[2][2]float64{{x, baseLine * size}, {x + size, baseLine * size}},
+		)
+	}
+	oldColor := color.NRGBA{220, 0, 0, 160}
+	newColor := color.NRGBA{0, 0, 220, 160}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		img := image.NewRGBA(image.Rect(0, 0, int(size)*canvases, int(size)))
+		draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+		renderLines(img, frame, renderOptions{penWidth: 1, color: color.Gray{200}})
+		for i, c := range []color.Color{oldColor, newColor} {
+			renderLayout(img, placed[i:i+1], renderOptions{
+				penWidth: opt.penWidth,
+				pen:      circular,
+				color:    c,
+			})
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(path, buf.Bytes(), 0666)
+	case ".svg":
+		var framePaths [][][2]float64
+		for _, l := range frame {
+			framePaths = append(framePaths, [][2]float64{l[0], l[1]})
+		}
+		svgColor := func(c color.NRGBA) string {
+			return fmt.Sprintf("rgba(%d,%d,%d,%.2f)", c.R, c.G, c.B, float64(c.A)/255)
+		}
+		paths := func(l placedLetter) [][][2]float64 {
+			return layoutPaths([]placedLetter{l}, pathOptions{tolerance: 0.2})
+		}
+		return exportSVGLayers([]svgLayer{
+			{paths: framePaths, color: "rgb(200,200,200)", width: 1},
+			{paths: paths(placed[0]), color: svgColor(oldColor), width: opt.penWidth},
+			{paths: paths(placed[1]), color: svgColor(newColor), width: opt.penWidth},
+		}, path, circular)
+	default:
+		return errors.New("diff image must be .png or .svg")
+	}
+}
+
+// diffImageName is the file name for the image of a changed letter, without
+// an extension.
+func diffImageName(r rune) string {
+	if r == notdef {
+		return "notdef"
+	}
+	return fmt.Sprintf("U+%04X", r)
+}
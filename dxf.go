@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+)
+
+type dxfOptions struct {
+	// units is the unit of all lengths in the options and of the laid out
+	// text. The file is always written in millimeters, see writeDXF.
+	units dxfUnit
+	// size is the height of the letter's canvas in units, for
+	// exportDXFLetter. Laid out text has its own sizes, see layoutText.
+	size float64
+	// tolerance is the maximum distance between a curve and the polyline that
+	// replaces it, in units.
+	tolerance float64
+	// dotRadius makes dots circles of this radius, in units. Dots are
+	// written as points if it is 0.
+	dotRadius float64
+}
+
+// dxfUnit is a unit of length, as the number of millimeters it is long.
+type dxfUnit float64
+
+const (
+	// dxfUnitless lengths are written to the file unchanged.
+	dxfUnitless   dxfUnit = 1
+	dxfInches     dxfUnit = 25.4
+	dxfFeet       dxfUnit = 304.8
+	dxfMillimeter dxfUnit = 1
+	dxfCentimeter dxfUnit = 10
+	dxfMeter      dxfUnit = 1000
+)
+
+// exportDXFText writes the text set in the given font as a DXF drawing. It is
+// set in the box with the layout options, see layoutText, in the options'
+// units and with y pointing down like on the canvas, see writeDXF. A box at
+// y = -baseLine*size puts the first line's base line at y = 0.
+func exportDXFText(f *font, text string, box layoutBox, layout layoutOptions, path string, opt dxfOptions) error {
+	return writeDXF(layoutText(f, text, box, layout), path, opt)
+}
+
+// exportDXFLetter writes a single letter as a DXF drawing, with the left of
+// the letter's canvas at x = 0 and its base line at y = 0.
+func exportDXFLetter(shape strokes, path string, opt dxfOptions) error {
+	if opt.size <= 0 {
+		return errors.New("DXF letter size must be positive")
+	}
+	return writeDXF([]placedLetter{{
+		y:     -baseLine * opt.size,
+		size:  opt.size,
+		shape: shape,
+	}}, path, opt)
+}
+
+// writeDXF writes the laid out letters, which use the options' units, as an
+// AutoCAD R12 DXF file which is understood by pretty much
+// every CAD and laser cutter program. It contains only an ENTITIES section:
+// lines become LINEs, curves become POLYLINEs and dots become POINTs or
+// CIRCLEs, depending on the options. DXF's y axis points up, unlike the
+// letter canvas'.
+//
+// R12 files cannot say which unit they use, programs reading them mostly
+// assume millimeters. This is why all coordinates are converted to
+// millimeters.
+func writeDXF(text []placedLetter, path string, opt dxfOptions) error {
+	if opt.tolerance <= 0 {
+		return errors.New("DXF curve tolerance must be positive")
+	}
+	if opt.units <= 0 {
+		return errors.New("DXF units must be positive")
+	}
+	mm := float64(opt.units)
+
+	var buf bytes.Buffer
+	w := &buf
+	pair := func(code int, value string) {
+		fmt.Fprintf(w, "%3d\n%s\n", code, value)
+	}
+	num := func(code int, f float64) {
+		pair(code, strconv.FormatFloat(f, 'f', -1, 64))
+	}
+
+	pair(0, "SECTION")
+	pair(2, "HEADER")
+	pair(9, "$ACADVER")
+	pair(1, "AC1009")
+	pair(0, "ENDSEC")
+
+	pair(0, "SECTION")
+	pair(2, "ENTITIES")
+	for _, l := range text {
+		toDXF := func(x, y float64) (float64, float64) {
+			return (l.x + x*l.size) * mm, -(l.y + y*l.size) * mm
+		}
+		vertex := func(x, y float64) {
+			x, y = toDXF(x, y)
+			num(10, x)
+			num(20, y)
+			num(30, 0)
+		}
+		for _, s := range l.shape {
+			switch s.typ {
+			case dot:
+				if opt.dotRadius > 0 {
+					pair(0, "CIRCLE")
+					pair(8, "0")
+					vertex(s.x1, s.y1)
+					num(40, opt.dotRadius*mm)
+				} else {
+					pair(0, "POINT")
+					pair(8, "0")
+					vertex(s.x1, s.y1)
+				}
+			case line:
+				pair(0, "LINE")
+				pair(8, "0")
+				x1, y1 := toDXF(s.x1, s.y1)
+				x2, y2 := toDXF(s.x2, s.y2)
+				num(10, x1)
+				num(20, y1)
+				num(30, 0)
+				num(11, x2)
+				num(21, y2)
+				num(31, 0)
+			case curve:
+				pair(0, "POLYLINE")
+				pair(8, "0")
+				pair(66, "1") // vertices follow
+				num(10, 0)
+				num(20, 0)
+				num(30, 0)
+				for _, p := range s.flatten(opt.tolerance / l.size) {
+					pair(0, "VERTEX")
+					pair(8, "0")
+					vertex(p[0], p[1])
+				}
+				pair(0, "SEQEND")
+				pair(8, "0")
+			default:
+				panic("unknown stroke type")
+			}
+		}
+	}
+	pair(0, "ENDSEC")
+	pair(0, "EOF")
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0666)
+}
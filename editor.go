@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"image"
 	"io"
 	"io/ioutil"
 	"math"
@@ -13,15 +14,31 @@ import (
 	"path/filepath"
 	"sort"
 	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/gonutz/prototype/draw"
 )
 
 func main() {
+	if runCommand(os.Args[1:]) {
+		return
+	}
+
 	const (
 		idle = iota
 		waitingForChar
 		copyingChar
+		showingCoverage
+		mergingFont
+		openingFile
+		savingFileAs
+		confirmingDiscard
+		choosingReference
+		editingPreview
+		showingOverview
 	)
 	mode := idle
 
@@ -30,43 +47,152 @@ func main() {
 	hideFrame := false
 	hideGrid := false
 
-	type penShape int
-	const (
-		rectangular penShape = iota
-		circular
-	)
-
 	pen := circular
 	penSize := 1
 	const penSizeChangeTimeOut = 4
 	penSizeChangeTime := 0
 
-	const baseLine = 2.0 / 3.0
 	gridSize := 0.1
 	useGrid := true
+	// dragged points snap to the ends of and points on other strokes, and to
+	// guides, independent of the grid. The snap wins over the grid.
+	useSnapping := true
+
+	coverageTarget := characterSets[0].name
+
+	// the overview shows all letters of a Unicode block at a time, with empty
+	// cells for those of the coverage target that are missing
+	const overviewCellSize = 60
+	overviewPage, overviewScroll := 0, 0
+
+	// the preview strip under the canvas shows a sample text with all letters
+	// as they are in the editor, previewSize is the height of a letter's
+	// canvas and previewPen the pen's size, in pixels
+	previewText := "The quick brown fox jumps over the lazy dog"
+	previewSize := 100
+	previewPen := 2
+
+	// every letter can have an image to trace, drawn under it
+	references := make(map[rune]referenceImage)
+
+	// the merge prompt asks for a font file to merge into the current one
+	var (
+		mergePath    string
+		mergeKeep    = keepFirst
+		mergeMessage string
+	)
+	mergeKeepText := []string{
+		keepFirst:       "the editor's letters",
+		keepLast:        "the file's letters",
+		keepMostStrokes: "the letters with more strokes",
+		failOnConflict:  "nothing, fail on conflicts",
+	}
+
+	// the file prompt asks for a font file to open or to save the font as, or
+	// for a letter's reference image
+	var (
+		filePrompt  string
+		fileMessage string
+		recentFiles []string
+	)
+	// status is shown in the status line at the bottom of the canvas, after
+	// the current file's path, e.g. when the font was saved
+	var status string
+	// Unsaved changes are written to the current file without asking when
+	// the editor closes or opens another file if autoSave is set. This is
+	// the case for the editor's own file, for new files and for files that
+	// the user saved in this session. Otherwise the editor asks first, in
+	// mode confirmingDiscard, and then calls afterConfirm. If the user drops
+	// the changes, discard is set.
+	var (
+		autoSave     bool
+		discard      bool
+		afterConfirm func()
+	)
 
 	var (
 		curLetter           rune
 		shape               strokes
 		allLetters          = make(map[rune]strokes)
+		advances            = make(map[rune]float64)
 		curX, curY          *float64
 		curMouseDx          int
 		curMouseDy          int
 		mouseInDeletionArea bool
 	)
 
-	settingsPath := filepath.Join(os.Getenv("APPDATA"), "stroke_font_editor.set")
+	// every letter has its own undo history, it stays when switching letters
+	histories := make(map[rune]*editHistory)
+	historyOf := func(r rune) *editHistory {
+		if histories[r] == nil {
+			histories[r] = &editHistory{}
+		}
+		return histories[r]
+	}
+	// dragStart is the current letter before a control point is dragged, the
+	// whole drag is undone at once
+	var dragStart letterState
+
+	// selected points are moved, scaled and rotated together by dragging them
+	// or the handles around them
+	selected := make(selection)
+	const (
+		noGroupDrag = iota
+		movingGroup
+		scalingGroup
+		rotatingGroup
+	)
+	var (
+		groupDrag = noGroupDrag
+		// groupShape is the letter when the group drag started, the drag's
+		// transformation is applied to it rather than adding up each frame's
+		groupShape  strokes
+		groupBounds bounds
+		// groupGrabX and groupGrabY are the canvas position of what was
+		// grabbed: a control point, the start of a stroke, the scaled corner
+		// or the rotate handle. groupMouseX and groupMouseY are where the mouse
+		// was on the screen at that time.
+		groupGrabX, groupGrabY   float64
+		groupMouseX, groupMouseY int
+		// selectingArea is true while the mouse is dragged from areaX,areaY to
+		// select all points in the rectangle
+		selectingArea bool
+		areaX, areaY  int
+	)
+
+	// with the freehand pen, the mouse path is recorded while the button is
+	// held and replaced by lines and curves that follow it when it is released
+	var (
+		freehand     bool
+		recording    bool
+		freehandPath [][2]float64
+	)
+
+	// settings and the default font file go into the user's config directory,
+	// or the working directory if there is none
+	configDir, _ := os.UserConfigDir()
+	if configDir != "" {
+		os.MkdirAll(configDir, 0777)
+	}
+	settingsPath := filepath.Join(configDir, "stroke_font_editor.set")
 	defer func() {
 		saveAppSettings(appSettings{
 			Letter:            curLetter,
 			PenShape:          int(pen),
 			PenSize:           penSize,
 			UseGrid:           useGrid,
+			DisableSnapping:   !useSnapping,
 			GridSize:          gridSize,
 			HideControlPoints: hideControlPoints,
 			HideBaseLine:      hideBaseLine,
 			HideFrame:         hideFrame,
 			HideGrid:          hideGrid,
+			CoverageTarget:    coverageTarget,
+			RecentFiles:       recentFiles,
+			References:        references,
+			PreviewText:       previewText,
+			PreviewSize:       previewSize,
+			PreviewPen:        previewPen,
 		}, settingsPath)
 	}()
 	if s, err := loadAppSettings(settingsPath); err == nil {
@@ -74,46 +200,242 @@ func main() {
 		pen = penShape(s.PenShape)
 		penSize = s.PenSize
 		useGrid = s.UseGrid
+		useSnapping = !s.DisableSnapping
 		gridSize = s.GridSize
 		hideControlPoints = s.HideControlPoints
 		hideBaseLine = s.HideBaseLine
 		hideFrame = s.HideFrame
 		hideGrid = s.HideGrid
+		if _, ok := findCharacterSet(s.CoverageTarget); ok {
+			coverageTarget = s.CoverageTarget
+		}
+		recentFiles = s.RecentFiles
+		if s.References != nil {
+			references = s.References
+		}
+		if s.PreviewSize > 0 && s.PreviewPen > 0 {
+			previewText = s.PreviewText
+			previewSize = s.PreviewSize
+			previewPen = s.PreviewPen
+		}
 	}
 
-	lastPath := filepath.Join(os.Getenv("APPDATA"), "stroke_font_editor.stf")
-	if l, err := importFile(lastPath); err == nil {
+	var info fontInfo
+	currentLetters := func() letters {
+		allLetters[curLetter] = shape
+		var l letters
+		for r, s := range allLetters {
+			l = append(l, letter{r: r, shape: s, advance: advances[r]})
+		}
+		return l
+	}
+	currentState := func() letterState {
+		return letterState{shape: shape, advance: advances[curLetter]}
+	}
+	// remember is called before the current letter is changed, for undoing it
+	remember := func() {
+		historyOf(curLetter).remember(currentState())
+	}
+	// rememberLetters is called before all letters in the list replace the
+	// editor's, it remembers those that change
+	rememberLetters := func(list letters) {
+		allLetters[curLetter] = shape
+		for _, l := range list {
+			old := letterState{shape: allLetters[l.r], advance: advances[l.r]}
+			if !old.equals(letterState{shape: l.shape, advance: l.advance}) {
+				historyOf(l.r).remember(old)
+			}
+		}
+	}
+	// savedFont is the font as it was opened or last saved, the status line
+	// marks the file if the editor's letters are different
+	var savedFont map[rune]letterState
+	markSaved := func() {
+		allLetters[curLetter] = shape
+		savedFont = make(map[rune]letterState)
+		for r, s := range allLetters {
+			if len(s) > 0 || advances[r] != 0 {
+				savedFont[r] = letterState{shape: s, advance: advances[r]}.copy()
+			}
+		}
+	}
+	unsaved := func() bool {
+		allLetters[curLetter] = shape
+		n := 0
+		for r, s := range allLetters {
+			if len(s) == 0 && advances[r] == 0 {
+				continue
+			}
+			n++
+			if !savedFont[r].equals(letterState{shape: s, advance: advances[r]}) {
+				return true
+			}
+		}
+		return n != len(savedFont)
+	}
+	// setFont replaces all letters in the editor, e.g. with an opened file's
+	setFont := func(list letters, i fontInfo) {
+		info = i
 		allLetters = make(map[rune]strokes)
-		for i := range l {
-			allLetters[l[i].r] = l[i].shape
+		advances = make(map[rune]float64)
+		for _, l := range list {
+			allLetters[l.r] = l.shape
+			advances[l.r] = l.advance
 		}
 		shape = make(strokes, len(allLetters[curLetter]))
 		copy(shape, allLetters[curLetter])
+		curX, curY = nil, nil
+		selected = make(selection)
+	}
+
+	// edit the font file given on the command line, otherwise the one that
+	// was edited last. A file that does not exist yet is created when the
+	// editor closes. A file that cannot be read is not overwritten, the editor
+	// does not start instead.
+	editorFontPath := filepath.Join(configDir, "stroke_font_editor.stf")
+	fontPath := editorFontPath
+	if len(recentFiles) > 0 {
+		fontPath = recentFiles[0]
+	}
+	if len(os.Args) > 2 {
+		fmt.Fprintln(os.Stderr, "usage: stroke_font_editor [font file]")
+		os.Exit(2)
+	}
+	if len(os.Args) == 2 {
+		fontPath = os.Args[1]
+	}
+	fontPath = absolutePath(fontPath)
+	autoSave = fontPath == absolutePath(editorFontPath)
+	if l, i, err := loadFont(fontPath); err == nil {
+		setFont(l, i)
+	} else if os.IsNotExist(err) {
+		autoSave = true
+	} else {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
 	}
+	recentFiles = addRecentFile(recentFiles, fontPath)
+	markSaved()
+	// if the window was closed without asking about unsaved changes, they
+	// are kept next to the file instead of overwriting it
 	defer func() {
-		allLetters[curLetter] = shape
-		var l letters
-		for r, s := range allLetters {
-			l = append(l, letter{r: r, shape: s})
+		if !unsaved() || discard {
+			return
+		}
+		path := fontPath
+		if !autoSave {
+			path = strings.TrimSuffix(path, filepath.Ext(path)) + ".unsaved.stf"
+			fmt.Fprintln(os.Stderr, "unsaved changes are written to", path)
+		}
+		if err := saveFont(currentLetters(), info, path); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
 		}
-		exportFile(l, lastPath)
 	}()
 
-	const windowW, windowH = 960, 800
-	check(draw.RunWindow("Stroke Font Editor", windowW, windowH, func(window draw.Window) {
+	switchToLetter := func(r rune) {
+		allLetters[curLetter] = make(strokes, len(shape))
+		copy(allLetters[curLetter], shape)
+
+		curLetter = r
+
+		shape = make(strokes, len(allLetters[curLetter]))
+		copy(shape, allLetters[curLetter])
+		selected = make(selection)
+	}
+
+	const windowW, windowH = 960, 960
+	// the letter's canvas is at the top-left, the preview strip below it
+	const canvasMin, canvasSize = 10, 780
+	const previewTop, previewHeight = canvasMin + canvasSize + 10, windowH - canvasSize - 30
+	// the title cannot change while the window is open, it names the file that
+	// the editor started with, the status line shows the current one
+	title := "Stroke Font Editor - " + filepath.Base(fontPath)
+	check(draw.RunWindow(title, windowW, windowH, func(window draw.Window) {
 		if window.WasKeyPressed(draw.KeyEscape) {
-			window.Close()
+			if mode == showingCoverage || mode == mergingFont ||
+				mode == openingFile || mode == savingFileAs ||
+				mode == choosingReference || mode == editingPreview ||
+				mode == showingOverview {
+				mode = idle
+			} else if mode == confirmingDiscard {
+				mode = idle
+				fileMessage = ""
+			} else if unsaved() && !autoSave {
+				mode = confirmingDiscard
+				afterConfirm = window.Close
+			} else {
+				window.Close()
+			}
+		}
+
+		controlDown := window.IsKeyDown(draw.KeyLeftControl) ||
+			window.IsKeyDown(draw.KeyRightControl)
+		shiftDown := window.IsKeyDown(draw.KeyLeftShift) ||
+			window.IsKeyDown(draw.KeyRightShift)
+
+		// Ctrl+S saves to the current file, Ctrl+Shift+S asks for a new one
+		// and so does a failed save, showing what went wrong
+		if window.WasKeyPressed(draw.KeyS) && controlDown && mode == idle {
+			fileMessage = ""
+			if !shiftDown {
+				if err := saveFont(currentLetters(), info, fontPath); err != nil {
+					fileMessage = err.Error()
+				} else {
+					markSaved()
+					autoSave = true
+					status = "saved " + time.Now().Format("15:04:05")
+				}
+			}
+			if shiftDown || fileMessage != "" {
+				mode = savingFileAs
+				filePrompt = fontPath
+			}
+		}
+		if window.WasKeyPressed(draw.KeyO) && controlDown && mode == idle {
+			mode = openingFile
+			filePrompt = ""
+			fileMessage = ""
+		}
+
+		// Ctrl+D writes the current letter as a DXF drawing next to the font
+		// file, named after the file and the letter's code point
+		if window.WasKeyPressed(draw.KeyD) && controlDown {
+			path := fmt.Sprintf(
+				"%s_U%04X.dxf",
+				strings.TrimSuffix(fontPath, filepath.Ext(fontPath)), uint32(curLetter),
+			)
+			err := exportDXFLetter(shape, path, dxfOptions{
+				units:     dxfMillimeter,
+				size:      100,
+				tolerance: 0.05,
+			})
+			if err != nil {
+				status = err.Error()
+			} else {
+				status = "wrote " + path
+			}
 		}
 
-		if window.WasKeyPressed(draw.KeyE) &&
-			(window.IsKeyDown(draw.KeyLeftControl) ||
-				window.IsKeyDown(draw.KeyRightControl)) {
-			allLetters[curLetter] = shape
-			var l letters
-			for r, s := range allLetters {
-				l = append(l, letter{r: r, shape: s})
+		// Ctrl+H writes the C header next to the font file, named after it.
+		// Holding Shift uses 8 instead of 16 bits per coordinate. The PROGMEM
+		// tables only take effect on AVR targets. For other options use the
+		// convert command.
+		if window.WasKeyPressed(draw.KeyH) && controlDown {
+			base := strings.TrimSuffix(fontPath, filepath.Ext(fontPath))
+			opt := cHeaderOptions{
+				name:    cIdentifier(filepath.Base(base)),
+				bits:    16,
+				progmem: true,
+			}
+			if shiftDown {
+				opt.bits = 8
+			}
+			path := base + ".h"
+			if err := exportCHeader(currentLetters(), path, opt); err != nil {
+				status = err.Error()
+			} else {
+				status = fmt.Sprintf("wrote %s (%d bit, PROGMEM on AVR)", path, opt.bits)
 			}
-			exportFile(l, "font.stf")
 		}
 
 		if !window.IsMouseDown(draw.LeftButton) {
@@ -124,17 +446,81 @@ func main() {
 						curX == &shape[s].x3 {
 						copy(shape[s:], shape[s+1:])
 						shape = shape[:len(shape)-1]
+						selected = make(selection)
 						break
 					}
 				}
 			}
+			// a dragged group is deleted as whole strokes
+			if mouseInDeletionArea && groupDrag != noGroupDrag {
+				shape = selected.without(shape)
+				selected = make(selection)
+			}
+			if (curX != nil || groupDrag != noGroupDrag) &&
+				!dragStart.equals(currentState()) {
+				historyOf(curLetter).remember(dragStart)
+			}
 			curX, curY = nil, nil
+			groupDrag = noGroupDrag
+		}
+
+		// undo and redo are not possible while dragging since the dragged
+		// points would be lost
+		if controlDown && mode == idle && curX == nil &&
+			groupDrag == noGroupDrag && !selectingArea && !recording {
+			var s letterState
+			ok := false
+			if window.WasKeyPressed(draw.KeyZ) {
+				s, ok = historyOf(curLetter).undo(currentState())
+			}
+			if window.WasKeyPressed(draw.KeyY) {
+				s, ok = historyOf(curLetter).redo(currentState())
+			}
+			if ok {
+				shape = s.shape
+				advances[curLetter] = s.advance
+				selected = make(selection)
+			}
 		}
 
 		drawDot := window.FillEllipse
 		if pen == rectangular {
 			drawDot = window.FillRect
 		}
+		// drawPolyline draws flattened strokes in screen coordinates with the
+		// current pen of the given size in pixels. Every segment is drawn as
+		// parallel lines, shifted along the axis that is most across it, as
+		// many as it takes to make it size pixels thick. The pen's shape is
+		// drawn at the points for round or square corners and ends.
+		drawPolyline := func(points [][2]int, size int, color draw.Color) {
+			for i, p := range points {
+				if size > 1 || len(points) == 1 {
+					drawDot(p[0]-size/2, p[1]-size/2, size, size, color)
+				}
+				if i == 0 {
+					continue
+				}
+				a := points[i-1]
+				dx, dy := p[0]-a[0], p[1]-a[1]
+				across := math.Max(math.Abs(float64(dx)), math.Abs(float64(dy)))
+				if across == 0 {
+					continue
+				}
+				// rounded, not rounded up, so 1 pixel pens stay 1 pixel thick
+				n := int(float64(size)*math.Hypot(float64(dx), float64(dy))/across + 0.5)
+				if n < 1 {
+					n = 1
+				}
+				for k := 0; k < n; k++ {
+					o := k - (n-1)/2
+					if math.Abs(float64(dx)) >= math.Abs(float64(dy)) {
+						window.DrawLine(a[0], a[1]+o, p[0], p[1]+o, color)
+					} else {
+						window.DrawLine(a[0]+o, a[1], p[0]+o, p[1], color)
+					}
+				}
+			}
+		}
 
 		penSizeChangeTime--
 		if penSizeChangeTime < 0 {
@@ -163,19 +549,32 @@ func main() {
 			return false
 		}
 
+		// the overview opens on the current letter's page
+		openOverview := func() {
+			mode = showingOverview
+			target, _ := findCharacterSet(coverageTarget)
+			overviewPage, overviewScroll = 0, 0
+			for i, page := range overviewPages(currentLetters(), target) {
+				for j, r := range page.runes {
+					if r == curLetter {
+						overviewPage = i
+						overviewScroll = j / ((windowW - 20) / overviewCellSize)
+					}
+				}
+			}
+		}
+
 		if mode == waitingForChar {
-			window.DrawText("Enter the new letter", 100, 100, draw.White)
+			if window.WasKeyPressed(draw.KeyF7) {
+				openOverview()
+				return
+			}
+			window.DrawText("Enter the new letter, F7 shows all letters", 100, 100, draw.White)
 			s := window.Characters()
 			if len(s) > 0 {
 				mode = idle
 				for _, r := range s {
-					allLetters[curLetter] = make(strokes, len(shape))
-					copy(allLetters[curLetter], shape)
-
-					curLetter = r
-
-					shape = make(strokes, len(allLetters[curLetter]))
-					copy(shape, allLetters[curLetter])
+					switchToLetter(r)
 					break
 				}
 			}
@@ -188,13 +587,479 @@ func main() {
 			if len(s) > 0 {
 				mode = idle
 				for _, r := range s {
+					remember()
 					orig := allLetters[r]
 					allLetters[curLetter] = make(strokes, len(orig))
 					copy(allLetters[curLetter], orig)
 					shape = make(strokes, len(allLetters[curLetter]))
 					copy(shape, allLetters[curLetter])
+					advances[curLetter] = advances[r]
+					selected = make(selection)
+					break
+				}
+			}
+			return
+		}
+
+		typeText := func(text *string) {
+			for _, r := range window.Characters() {
+				if unicode.IsPrint(r) {
+					*text += string(r)
+				}
+			}
+			if window.WasKeyPressed(draw.KeyBackspace) && len(*text) > 0 {
+				_, n := utf8.DecodeLastRuneInString(*text)
+				*text = (*text)[:len(*text)-n]
+			}
+		}
+		enterPressed := window.WasKeyPressed(draw.KeyEnter) ||
+			window.WasKeyPressed(draw.KeyNumEnter)
+
+		if mode == openingFile {
+			typeText(&filePrompt)
+			// unsaved changes are handled before another font is opened, just
+			// as when the editor closes
+			var open func(path string)
+			open = func(path string) {
+				drop := discard
+				discard = false
+				if unsaved() && !drop && !autoSave {
+					mode = confirmingDiscard
+					afterConfirm = func() {
+						mode = openingFile
+						open(path)
+					}
+					return
+				}
+				var err error
+				if unsaved() && !drop {
+					err = saveFont(currentLetters(), info, fontPath)
+				}
+				var list letters
+				var i fontInfo
+				if err == nil {
+					list, i, err = loadFont(path)
+				}
+				if err != nil {
+					fileMessage = err.Error()
+					return
+				}
+				setFont(list, i)
+				markSaved()
+				histories = make(map[rune]*editHistory)
+				fontPath = absolutePath(path)
+				autoSave = false
+				recentFiles = addRecentFile(recentFiles, path)
+				status = ""
+				mode = idle
+			}
+			if enterPressed {
+				open(filePrompt)
+			}
+			window.DrawText("Enter the font file to open, Escape to go back", 100, 100, draw.White)
+			window.DrawText(filePrompt+"_", 100, 130, draw.White)
+			window.DrawText(fileMessage, 100, 160, draw.LightGray)
+			y := 220
+			window.DrawText("Recent files (click to open):", 100, y, draw.White)
+			y += 30
+			for _, path := range recentFiles {
+				w, h := window.GetTextSize(path)
+				mx, my := window.MousePosition()
+				contains := func(xx, yy int) bool {
+					return xx >= 100 && yy >= y && xx < 100+w && yy < y+h
+				}
+				color := draw.White
+				if contains(mx, my) {
+					color = draw.LightGray
+				}
+				window.DrawText(path, 100, y, color)
+				for _, c := range window.Clicks() {
+					if c.Button == draw.LeftButton && contains(c.X, c.Y) {
+						open(path)
+					}
+				}
+				y += h + 5
+			}
+			return
+		}
+
+		if mode == savingFileAs {
+			typeText(&filePrompt)
+			if enterPressed {
+				if err := saveFont(currentLetters(), info, filePrompt); err != nil {
+					fileMessage = err.Error()
+				} else {
+					markSaved()
+					fontPath = absolutePath(filePrompt)
+					autoSave = true
+					recentFiles = addRecentFile(recentFiles, fontPath)
+					status = "saved " + time.Now().Format("15:04:05")
+					mode = idle
+				}
+			}
+			window.DrawText("Enter the file to save the font as, Escape to go back", 100, 100, draw.White)
+			window.DrawText(filePrompt+"_", 100, 130, draw.White)
+			window.DrawText(fileMessage, 100, 160, draw.LightGray)
+			return
+		}
+
+		// S saves the changes to the current file, D drops them, either way the
+		// editor goes on closing or opening a file
+		if mode == confirmingDiscard {
+			if window.WasKeyPressed(draw.KeyS) {
+				if err := saveFont(currentLetters(), info, fontPath); err != nil {
+					fileMessage = err.Error()
+				} else {
+					markSaved()
+					fileMessage = ""
+					mode = idle
+					afterConfirm()
+				}
+			}
+			if window.WasKeyPressed(draw.KeyD) {
+				discard = true
+				fileMessage = ""
+				mode = idle
+				afterConfirm()
+			}
+			window.DrawText("The font has unsaved changes, overwrite this file?", 100, 100, draw.White)
+			window.DrawText(fontPath, 100, 130, draw.White)
+			window.DrawText("S to save, D to discard the changes, Escape to go back", 100, 160, draw.White)
+			window.DrawText(fileMessage, 100, 190, draw.LightGray)
+			return
+		}
+
+		// drawPreview draws the sample text into the preview strip, the mouse
+		// wheel over it zooms and with shift changes the pen size
+		drawPreview := func() {
+			x0, y0, w, h := canvasMin, previewTop, windowW-2*canvasMin, previewHeight
+			window.FillRect(x0, y0, w, h, draw.White)
+			mx, my := window.MousePosition()
+			if mx >= x0 && my >= y0 && mx < x0+w && my < y0+h {
+				if wheel := window.MouseWheelY(); wheel > 0 || wheel < 0 {
+					if shiftDown {
+						previewPen += int(wheel)
+						if previewPen < 1 {
+							previewPen = 1
+						}
+						if previewPen > 50 {
+							previewPen = 50
+						}
+					} else {
+						previewSize = int(float64(previewSize)*math.Pow(1.1, wheel) + 0.5)
+						if previewSize < 10 {
+							previewSize = 10
+						}
+						if previewSize > 600 {
+							previewSize = 600
+						}
+					}
+				}
+			}
+
+			// the letters' canvases are centered in the strip, what does not
+			// fit is cut off
+			size := float64(previewSize)
+			placed := layoutText(
+				newFont(currentLetters()),
+				previewText,
+				layoutBox{x: float64(x0 + 10), y: float64(y0) + float64(h-previewSize)/2},
+				layoutOptions{size: size},
+			)
+			inside := func(x, y int) bool {
+				return x-previewPen/2 >= x0 && y-previewPen/2 >= y0 &&
+					x+previewPen-previewPen/2 <= x0+w && y+previewPen-previewPen/2 <= y0+h
+			}
+			for _, l := range placed {
+				for _, s := range l.shape {
+					// the parts of the stroke outside the strip are left out
+					var run [][2]int
+					for _, p := range s.flatten(0.5 / size) {
+						x := int(l.x + p[0]*l.size + 0.5)
+						y := int(l.y + p[1]*l.size + 0.5)
+						if inside(x, y) {
+							run = append(run, [2]int{x, y})
+						} else {
+							drawPolyline(run, previewPen, draw.Black)
+							run = run[:0]
+						}
+					}
+					drawPolyline(run, previewPen, draw.Black)
+				}
+			}
+
+			if previewText == "" {
+				window.DrawText("Click here or press F6 to enter a sample text", x0+10, y0+10, draw.Gray)
+			}
+			info := fmt.Sprintf("size %d, pen %d (mouse wheel, Shift for pen)", previewSize, previewPen)
+			tw, th := window.GetTextSize(info)
+			window.DrawText(info, x0+w-tw-5, y0+h-th-5, draw.Gray)
+		}
+
+		if mode == editingPreview {
+			typeText(&previewText)
+			if enterPressed {
+				mode = idle
+			}
+			window.DrawText("Enter the sample text for the preview, Enter or Escape when done", 100, 100, draw.White)
+			window.DrawText(previewText+"_", 100, 130, draw.White)
+			drawPreview()
+			return
+		}
+
+		if mode == choosingReference {
+			typeText(&filePrompt)
+			if enterPressed {
+				if filePrompt == "" {
+					delete(references, curLetter)
+					mode = idle
+				} else if _, _, err := window.ImageSize(filePrompt); err != nil {
+					fileMessage = err.Error()
+				} else {
+					path := filePrompt
+					if abs, err := filepath.Abs(path); err == nil {
+						path = abs
+					}
+					// a new image for the letter keeps the old one's placement
+					ref, ok := references[curLetter]
+					if ok {
+						ref.Path = path
+					} else {
+						ref = newReferenceImage(path)
+					}
+					references[curLetter] = ref
+					mode = idle
+				}
+			}
+			window.DrawText("Enter a PNG or JPEG image to trace on, nothing to remove it, Escape to go back", 100, 100, draw.White)
+			window.DrawText(filePrompt+"_", 100, 130, draw.White)
+			window.DrawText(fileMessage, 100, 160, draw.LightGray)
+			window.DrawText(
+				"On the canvas, Ctrl+Arrow keys move the image, Ctrl+Page Up/Down\n"+
+					"scales it and Ctrl+Shift+Page Up/Down changes its opacity.",
+				100, 220, draw.LightGray,
+			)
+			return
+		}
+
+		if mode == mergingFont {
+			typeText(&mergePath)
+			if window.WasKeyPressed(draw.KeyTab) {
+				mergeKeep = (mergeKeep + 1) % mergePolicy(len(mergeKeepText))
+			}
+			if enterPressed {
+				list, _, err := loadFont(mergePath)
+				if err == nil {
+					var merged letters
+					var report mergeReport
+					merged, report, err = mergeFonts(
+						[]string{"editor", mergePath},
+						[]letters{currentLetters(), list},
+						mergeKeep,
+					)
+					if err == nil {
+						rememberLetters(merged)
+						setFont(merged, info)
+						mergeMessage = report.String()
+					}
+				}
+				if err != nil {
+					mergeMessage = err.Error()
+				}
+			}
+			window.DrawText("Enter the font file to merge, Escape to go back", 100, 100, draw.White)
+			window.DrawText(mergePath+"_", 100, 130, draw.White)
+			window.DrawText(
+				"Keep "+mergeKeepText[mergeKeep]+" on conflicts (Tab to change)",
+				100, 160, draw.White,
+			)
+			window.DrawText(mergeMessage, 100, 200, draw.LightGray)
+			return
+		}
+
+		if mode == showingOverview {
+			target, _ := findCharacterSet(coverageTarget)
+			pages := overviewPages(currentLetters(), target)
+			if window.WasKeyPressed(draw.KeyTab) {
+				for i, set := range characterSets {
+					if set.name == coverageTarget {
+						coverageTarget = characterSets[(i+1)%len(characterSets)].name
+						break
+					}
+				}
+				overviewPage, overviewScroll = 0, 0
+			}
+			if button("Back", windowW-buttonW-10, 10) ||
+				window.WasKeyPressed(draw.KeyF7) {
+				mode = idle
+				return
+			}
+			if len(pages) == 0 {
+				window.DrawText("There are no letters yet", 10, 60, draw.White)
+				return
+			}
+			if (button("Previous Block", 10, 10) ||
+				window.WasKeyPressed(draw.KeyPageUp)) && overviewPage > 0 {
+				overviewPage--
+				overviewScroll = 0
+			}
+			if (button("Next Block", 20+buttonW, 10) ||
+				window.WasKeyPressed(draw.KeyPageDown)) && overviewPage < len(pages)-1 {
+				overviewPage++
+				overviewScroll = 0
+			}
+			if overviewPage >= len(pages) {
+				overviewPage = len(pages) - 1
+			}
+			page := pages[overviewPage]
+
+			// the rows of cells scroll with the mouse wheel
+			const top = 80
+			perRow := (windowW - 20) / overviewCellSize
+			rows := (len(page.runes) + perRow - 1) / perRow
+			visibleRows := (windowH - top - 10) / overviewCellSize
+			overviewScroll -= int(window.MouseWheelY())
+			if overviewScroll > rows-visibleRows {
+				overviewScroll = rows - visibleRows
+			}
+			if overviewScroll < 0 {
+				overviewScroll = 0
+			}
+
+			f := newFont(currentLetters())
+			header := fmt.Sprintf(
+				"%s (%d of %d), empty cells are missing from %s (Tab to change)",
+				page.name, overviewPage+1, len(pages), target.name,
+			)
+			mx, my := window.MousePosition()
+			for i, r := range page.runes {
+				row := i/perRow - overviewScroll
+				if row < 0 || row >= visibleRows {
+					continue
+				}
+				w := overviewCellSize - 4
+				x := 10 + (i%perRow)*overviewCellSize
+				y := top + row*overviewCellSize
+				contains := func(xx, yy int) bool {
+					return xx >= x && yy >= y && xx < x+w && yy < y+w
+				}
+
+				// white space is covered without a glyph, like in coverage
+				l := f.glyphs[r]
+				if hasGlyph(l) || unicode.IsSpace(r) {
+					window.FillRect(x, y, w, w, draw.White)
+					base := y + int(baseLine*float64(w)+0.5)
+					window.DrawLine(x, base, x+w, base, draw.LightPurple)
+					toCell := func(p [2]float64) (int, int) {
+						return x + int(p[0]*float64(w)+0.5), y + int(p[1]*float64(w)+0.5)
+					}
+					for _, s := range l.shape {
+						points := s.flatten(1 / float64(w))
+						if len(points) == 1 {
+							px, py := toCell(points[0])
+							window.FillRect(px-1, py-1, 2, 2, draw.Black)
+						}
+						for j := 1; j < len(points); j++ {
+							ax, ay := toCell(points[j-1])
+							bx, by := toCell(points[j])
+							window.DrawLine(ax, ay, bx, by, draw.Black)
+						}
+					}
+				} else {
+					window.FillRect(x, y, w, w, draw.DarkGray)
+					text := string(r)
+					tw, th := window.GetTextSize(text)
+					window.DrawText(text, x+(w-tw)/2, y+(w-th)/2, draw.LightGray)
+				}
+
+				if r == curLetter {
+					window.DrawRect(x-2, y-2, w+4, w+4, draw.LightBlue)
+				}
+				if contains(mx, my) {
+					window.DrawRect(x-1, y-1, w+2, w+2, draw.Green)
+					header = fmt.Sprintf("U+%04X %s", r, letterName(r))
+				}
+				for _, c := range window.Clicks() {
+					if c.Button == draw.LeftButton && contains(c.X, c.Y) {
+						switchToLetter(r)
+						mode = idle
+					}
+				}
+			}
+			window.DrawText(header, 10, 50, draw.White)
+			return
+		}
+
+		if mode == showingCoverage {
+			for i, set := range characterSets {
+				x := 10 + i*(buttonW+10)
+				if button(set.name, x, 10) {
+					coverageTarget = set.name
+				}
+				if set.name == coverageTarget {
+					window.DrawRect(x-2, 8, buttonW+4, buttonH+4, draw.White)
+				}
+			}
+			if button("Back", windowW-buttonW-10, 10) ||
+				window.WasKeyPressed(draw.KeyF3) {
+				mode = idle
+			}
+
+			target, _ := findCharacterSet(coverageTarget)
+			report := coverage(currentLetters(), target)
+			y := 60
+			window.DrawText(fmt.Sprintf(
+				"%s: %d of %d (%.1f%%)",
+				target.name, report.covered, len(target.runes), report.percentage(),
+			), 10, y, draw.White)
+			y += 40
+
+			// list the runes in the font by block, cut off at the window border
+			for _, b := range report.blocks {
+				text := fmt.Sprintf("%s (%d): ", b.block.name, len(b.runes))
+				for i := range b.runes {
+					next := runeList(b.runes[i:i+1]) + " "
+					if w, _ := window.GetTextSize(text + next + "..."); w > windowW-20 {
+						text += "..."
+						break
+					}
+					text += next
+				}
+				_, h := window.GetTextSize(text)
+				window.DrawText(text, 10, y, draw.LightGray)
+				y += h + 5
+			}
+			y += 20
+
+			window.DrawText("Missing (click to create):", 10, y, draw.White)
+			y += 30
+			const cellSize = 36
+			perRow := (windowW - 20) / cellSize
+			for i, r := range report.missing {
+				x := 10 + (i%perRow)*cellSize
+				y := y + (i/perRow)*cellSize
+				if y+cellSize > windowH {
 					break
 				}
+				const w = cellSize - 2
+				mx, my := window.MousePosition()
+				contains := func(xx, yy int) bool {
+					return xx >= x && yy >= y && xx < x+w && yy < y+w
+				}
+				color := draw.White
+				if contains(mx, my) {
+					color = draw.LightGray
+				}
+				window.FillRect(x, y, w, w, color)
+				text := string(r)
+				tw, th := window.GetTextSize(text)
+				window.DrawText(text, x+(w-tw)/2, y+(w-th)/2, draw.Black)
+				for _, c := range window.Clicks() {
+					if c.Button == draw.LeftButton && contains(c.X, c.Y) {
+						switchToLetter(r)
+						mode = idle
+					}
+				}
 			}
 			return
 		}
@@ -208,18 +1073,90 @@ func main() {
 			mode = copyingChar
 			return
 		}
-		window.DrawText(
-			"Letter: "+fmt.Sprint(curLetter)+" ("+string(curLetter)+")",
-			windowW-buttonW-10, 10,
-			draw.White,
-		)
+		letterText := "Letter: " + fmt.Sprint(curLetter) + " (" + string(curLetter) + ")"
+		if curLetter == notdef {
+			letterText = "Letter: .notdef"
+		}
+		window.DrawText(letterText, windowW-buttonW-10, 10, draw.White)
+		// the glyph for missing letters starts out as the default box so there
+		// is something to edit
+		if button("Edit .notdef", windowW-buttonW-10, 500) ||
+			window.WasKeyPressed(draw.KeyF4) {
+			switchToLetter(notdef)
+			if len(shape) == 0 && advances[notdef] == 0 {
+				remember()
+				shape = append(strokes(nil), defaultNotdef.shape...)
+				advances[notdef] = defaultNotdef.advance
+			}
+		}
+		if button("Merge Font", windowW-buttonW-10, 540) {
+			mode = mergingFont
+			mergeMessage = ""
+			return
+		}
+		if button("Coverage", windowW-buttonW-10, 270) ||
+			window.WasKeyPressed(draw.KeyF3) {
+			mode = showingCoverage
+			return
+		}
+		if window.WasKeyPressed(draw.KeyF7) {
+			openOverview()
+			return
+		}
+		if button("Reference Image", windowW-buttonW-10, 305) ||
+			window.WasKeyPressed(draw.KeyF5) {
+			mode = choosingReference
+			filePrompt = references[curLetter].Path
+			fileMessage = ""
+			return
+		}
+
+		// Ctrl with the arrow keys moves the letter's reference image, with
+		// Page Up and Down it is scaled and with Shift, Page Up and Down its
+		// opacity changes
+		if ref, ok := references[curLetter]; ok && controlDown {
+			step := 0.005
+			if shiftDown {
+				step = 0.05
+			}
+			if window.WasKeyPressed(draw.KeyLeft) {
+				ref.X -= step
+			}
+			if window.WasKeyPressed(draw.KeyRight) {
+				ref.X += step
+			}
+			if window.WasKeyPressed(draw.KeyUp) {
+				ref.Y -= step
+			}
+			if window.WasKeyPressed(draw.KeyDown) {
+				ref.Y += step
+			}
+			if window.WasKeyPressed(draw.KeyPageUp) {
+				if shiftDown {
+					ref.Opacity = math.Min(1, ref.Opacity+0.1)
+				} else {
+					ref.Scale *= 1.05
+				}
+			}
+			if window.WasKeyPressed(draw.KeyPageDown) {
+				if shiftDown {
+					ref.Opacity = math.Max(0, ref.Opacity-0.1)
+				} else {
+					ref.Scale /= 1.05
+				}
+			}
+			references[curLetter] = ref
+		}
 		if button("New Dot", windowW-buttonW-10, 140) {
+			remember()
 			shape = append(shape, stroke{typ: dot, x1: 0, y1: 0})
 		}
 		if button("New Line", windowW-buttonW-10, 185) {
+			remember()
 			shape = append(shape, stroke{typ: line, x1: 0, y1: 0, x2: 0.1, y2: 0})
 		}
 		if button("New Curve", windowW-buttonW-10, 230) {
+			remember()
 			shape = append(shape, stroke{typ: curve,
 				x1: 0, y1: 0,
 				x2: 0.1, y2: 0.1,
@@ -227,6 +1164,46 @@ func main() {
 			})
 		}
 
+		// transform the selection, or the whole letter if there is none, around
+		// its center, holding shift does the opposite
+		{
+			b := shape.bounds()
+			if len(selected) > 1 {
+				b = selected.bounds(shape)
+			}
+			cx, cy := (b.minX+b.maxX)/2, (b.minY+b.maxY)/2
+			const step = 15 * math.Pi / 180
+			m := identity
+			if window.WasKeyPressed(draw.KeyM) && !controlDown {
+				m = mirrorX(cx)
+				if shiftDown {
+					m = mirrorY(cy)
+				}
+			}
+			if window.WasKeyPressed(draw.KeyR) && !controlDown {
+				m = rotation(step).around(cx, cy)
+				if shiftDown {
+					m = rotation(-step).around(cx, cy)
+				}
+			}
+			if window.WasKeyPressed(draw.KeyO) && !controlDown {
+				m = shear(step, baseLine)
+				if shiftDown {
+					m = shear(-step, baseLine)
+				}
+			}
+			if m != identity && !b.empty() {
+				remember()
+				if len(selected) > 1 {
+					shape = selected.transformed(shape, m)
+				} else {
+					for i := range shape {
+						shape[i].transform(m)
+					}
+				}
+			}
+		}
+
 		if window.WasKeyPressed(draw.KeyTab) {
 			hideControlPoints = !hideControlPoints
 			hideBaseLine = !hideBaseLine
@@ -247,6 +1224,13 @@ func main() {
 		if window.WasKeyPressed(draw.KeyG) {
 			useGrid = !useGrid
 		}
+		if window.WasKeyPressed(draw.KeyS) && !controlDown {
+			useSnapping = !useSnapping
+		}
+		if window.WasKeyPressed(draw.KeyF) && !controlDown && !recording {
+			freehand = !freehand
+			selected = make(selection)
+		}
 		if window.WasKeyPressed(draw.KeyNumAdd) {
 			n := int(1.0/gridSize + 0.5)
 			gridSize = 1.0 / float64(n+1)
@@ -322,9 +1306,21 @@ func main() {
 			}
 		}
 
-		// deletion area
+		drawPreview()
+		if window.WasKeyPressed(draw.KeyF6) {
+			mode = editingPreview
+			return
+		}
+		for _, c := range window.Clicks() {
+			if c.Button == draw.LeftButton && c.Y >= previewTop && c.Y < previewTop+previewHeight {
+				mode = editingPreview
+				return
+			}
+		}
+
+		// deletion area, at the bottom of the side column
 		{
-			x, y := windowW-10-buttonW, windowH-10-buttonW
+			x, y := windowW-10-buttonW, canvasMin+canvasSize-buttonW
 			window.FillRect(x, y, buttonW, buttonW, draw.DarkRed)
 			text := "Drag here\nto delete\nsomething"
 			tw, th := window.GetTextSize(text)
@@ -334,7 +1330,6 @@ func main() {
 		}
 
 		// draw the current letter
-		const canvasMin, canvasSize = 10, windowH - 20
 		toScreen := func(t float64) int {
 			return int(canvasMin + canvasSize*t + 0.5)
 		}
@@ -348,24 +1343,165 @@ func main() {
 			}
 			return float64(d) / (canvasSize - 1)
 		}
+		// toCanvas is like fromScreen but does not stop at the canvas' border
+		toCanvas := func(s int) float64 {
+			return float64(s-canvasMin) / canvasSize
+		}
+		startGroupDrag := func(kind int, grabX, grabY float64) {
+			groupDrag = kind
+			dragStart = currentState().copy()
+			groupShape = dragStart.shape
+			groupBounds = selected.bounds(shape)
+			groupGrabX, groupGrabY = grabX, grabY
+			groupMouseX, groupMouseY = window.MousePosition()
+		}
+
+		// set the advance and side bearings of all letters, with the current
+		// pen, and move their strokes accordingly
+		if button("Auto Metrics", windowW-buttonW-10, 460) {
+			rule := defaultSpacing
+			rule.penWidth = float64(penSize) / canvasSize
+			spaced := autoMetrics(currentLetters(), rule)
+			rememberLetters(spaced)
+			for _, l := range spaced {
+				allLetters[l.r] = l.shape
+				advances[l.r] = l.advance
+			}
+			shape = make(strokes, len(allLetters[curLetter]))
+			copy(shape, allLetters[curLetter])
+			curX, curY = nil, nil
+		}
 
 		// clear background
 		window.FillRect(canvasMin, canvasMin, canvasSize, canvasSize, draw.White)
 
+		// the reference image goes under everything else, white is drawn over
+		// it to make it as transparent as wanted
+		if ref, ok := references[curLetter]; ok {
+			if w, h, err := window.ImageSize(ref.Path); err == nil {
+				canvas := image.Rect(
+					canvasMin, canvasMin,
+					canvasMin+canvasSize, canvasMin+canvasSize,
+				)
+				if src, dest, ok := ref.visiblePart(w, h, canvas); ok {
+					window.DrawImageFilePart(
+						ref.Path,
+						src.Min.X, src.Min.Y, src.Dx(), src.Dy(),
+						dest.Min.X, dest.Min.Y, dest.Dx(), dest.Dy(),
+						0,
+					)
+					window.FillRect(
+						dest.Min.X, dest.Min.Y, dest.Dx(), dest.Dy(),
+						draw.RGBA(1, 1, 1, float32(1-ref.Opacity)),
+					)
+				}
+			}
+		}
+
 		alignWithGrid := func(x float64) float64 {
 			return float64(int(x/gridSize+0.5)) * gridSize
 		}
 
+		// snapped is where a dragged point snapped to in this frame, it is
+		// shown on the canvas
+		var snapped *snap
+		snapAt := func(x, y float64, skip map[int]bool) (snap, bool) {
+			if !useSnapping {
+				return snap{}, false
+			}
+			guides := snapGuides{ys: []float64{baseLine}}
+			if advances[curLetter] != 0 {
+				guides.xs = append(guides.xs, advances[curLetter])
+			}
+			return findSnap(shape, skip, guides, x, y, 8.0/canvasSize)
+		}
+
 		if curX != nil && curY != nil {
 			mx, my := window.MousePosition()
 			sx, sy := mx-curMouseDx, my-curMouseDy
 			x, y := fromScreen(sx), fromScreen(sy)
-			if useGrid {
+			// a point does not snap to its own stroke
+			skip := make(map[int]bool)
+			for i := range shape {
+				if curX == &shape[i].x1 || curX == &shape[i].x2 || curX == &shape[i].x3 {
+					skip[i] = true
+				}
+			}
+			if s, ok := snapAt(x, y, skip); ok {
+				x, y = s.x, s.y
+				snapped = &s
+			} else if useGrid {
 				x, y = alignWithGrid(x), alignWithGrid(y)
 			}
 			*curX, *curY = x, y
 		}
 
+		// the grid applies to the group as a whole: the grabbed point of a
+		// moved group or the dragged corner snaps to it and rotations snap to
+		// 15 degree steps. A moved group also snaps if any of its strokes' ends
+		// comes close to something, this wins over the grid.
+		if groupDrag != noGroupDrag {
+			mx, my := window.MousePosition()
+			rawX := groupGrabX + float64(mx-groupMouseX)/canvasSize
+			rawY := groupGrabY + float64(my-groupMouseY)/canvasSize
+			x, y := rawX, rawY
+			if useGrid && groupDrag != rotatingGroup {
+				x, y = alignWithGrid(x), alignWithGrid(y)
+			}
+			b := groupBounds
+			m := identity
+			switch groupDrag {
+			case movingGroup:
+				m = translation(x-groupGrabX, y-groupGrabY)
+				skip := make(map[int]bool)
+				for _, i := range selected.strokes() {
+					skip[i] = true
+				}
+				moved := translation(rawX-groupGrabX, rawY-groupGrabY)
+				var best snap
+				var bestX, bestY float64
+				found := false
+				for ref := range selected {
+					s := groupShape[ref.stroke]
+					if ref.point != 0 && ref.point != s.pointCount()-1 {
+						continue
+					}
+					px, py := s.point(ref.point)
+					ex, ey := moved.apply(*px, *py)
+					if sn, ok := snapAt(ex, ey, skip); ok && (!found ||
+						sn.kind < best.kind ||
+						sn.kind == best.kind && sn.distance < best.distance) {
+						best, bestX, bestY = sn, ex, ey
+						found = true
+					}
+				}
+				if found {
+					m = moved.then(translation(best.x-bestX, best.y-bestY))
+					snapped = &best
+				}
+			case scalingGroup:
+				// the opposite corner stays in place
+				fx, fy := b.minX+b.maxX-groupGrabX, b.minY+b.maxY-groupGrabY
+				sx, sy := 1.0, 1.0
+				if groupGrabX != fx {
+					sx = (x - fx) / (groupGrabX - fx)
+				}
+				if groupGrabY != fy {
+					sy = (y - fy) / (groupGrabY - fy)
+				}
+				m = scaling(sx, sy).around(fx, fy)
+			case rotatingGroup:
+				cx, cy := (b.minX+b.maxX)/2, (b.minY+b.maxY)/2
+				angle := math.Atan2(y-cy, x-cx) - math.Atan2(groupGrabY-cy, groupGrabX-cx)
+				if useGrid {
+					const step = 15 * math.Pi / 180
+					angle = math.Floor(angle/step+0.5) * step
+				}
+				m = rotation(angle).around(cx, cy)
+			}
+			shape = selected.transformed(groupShape, m)
+		}
+
 		// draw grid
 		if useGrid && !hideGrid {
 			gridColor := draw.RGB(0.9, 0.9, 1)
@@ -379,14 +1515,19 @@ func main() {
 			}
 		}
 
-		// draw draggable control points
+		// draw draggable control points, selected ones in blue, and remember
+		// which one was clicked
+		var clickedPoint *pointRef
 		for i := range shape {
-			stroke := &shape[i]
-			p := func(px, py *float64) {
-				x, y := *px, *py
+			for k := 0; k < shape[i].pointCount(); k++ {
+				ref := pointRef{stroke: i, point: k}
+				px, py := shape[i].point(k)
 				m := penSize + 10
-				sx, sy := toScreen(x), toScreen(y)
+				sx, sy := toScreen(*px), toScreen(*py)
 				fill, outline := draw.RGB(1, 0.8, 0.8), draw.RGB(1, 0.5, 0.5)
+				if selected[ref] {
+					fill, outline = draw.RGB(0.8, 0.8, 1), draw.RGB(0.5, 0.5, 1)
+				}
 				mx, my := window.MousePosition()
 				contains := func(x, y int) bool {
 					return x >= sx-m-1 && y >= sy-m-1 &&
@@ -394,17 +1535,10 @@ func main() {
 				}
 				if contains(mx, my) {
 					fill, outline = draw.RGB(0.8, 1, 0.8), draw.RGB(0.5, 1, 0.5)
-					// if nothing is selected and the mouse was clicked on this
-					// control point, make it current
-					if curX == nil && curY == nil &&
-						window.IsMouseDown(draw.LeftButton) {
-						for _, c := range window.Clicks() {
-							if c.Button == draw.LeftButton && contains(c.X, c.Y) {
-								curX, curY = px, py
-								curMouseDx = mx - sx
-								curMouseDy = my - sy
-							}
-						}
+				}
+				for _, c := range window.Clicks() {
+					if c.Button == draw.LeftButton && contains(c.X, c.Y) {
+						clickedPoint = &ref
 					}
 				}
 				if !hideControlPoints {
@@ -412,13 +1546,6 @@ func main() {
 					window.DrawRect(sx-m-1, sy-m-1, 3+2*m, 3+2*m, outline)
 				}
 			}
-			p(&stroke.x1, &stroke.y1)
-			if stroke.typ != dot {
-				p(&stroke.x2, &stroke.y2)
-			}
-			if stroke.typ == curve {
-				p(&stroke.x3, &stroke.y3)
-			}
 		}
 
 		// draw base line
@@ -430,69 +1557,252 @@ func main() {
 				toScreen(baseLine),
 				draw.Purple,
 			)
+			// the advance is drawn lighter if it is only estimated
+			advance := newFont(letters{{
+				r:       curLetter,
+				shape:   shape,
+				advance: advances[curLetter],
+			}}).advance(curLetter)
+			color := draw.LightBlue
+			if advances[curLetter] != 0 {
+				color = draw.DarkBlue
+			}
+			if advance <= 1 {
+				x := toScreen(advance)
+				window.DrawLine(x, canvasMin, x, canvasMin+canvasSize, color)
+			}
 		}
 
 		// draw letter
 		for i, stroke := range shape {
-			switch stroke.typ {
-			case dot:
+			var points [][2]int
+			for _, p := range stroke.flatten(0.5 / canvasSize) {
+				points = append(points, [2]int{toScreen(p[0]), toScreen(p[1])})
+			}
+			drawPolyline(points, penSize, draw.Black)
+			if !hideControlPoints {
 				x, y := toScreen(stroke.x1), toScreen(stroke.y1)
-				drawDot(x-penSize/2, y-penSize/2, penSize, penSize, draw.Black)
+				if stroke.typ != dot {
+					x = toScreen((stroke.x1 + stroke.x2) / 2)
+					y = toScreen((stroke.y1 + stroke.y2) / 2)
+				}
+				window.DrawText(strconv.Itoa(i), x, y, draw.DarkGreen)
+			}
+		}
+
+		// the selection's frame has handles to scale it at the corners and one
+		// to rotate it above the top, a click grabs one of these, a control
+		// point, a stroke or starts selecting an area, in that order
+		{
+			type handle struct {
+				kind         int
+				sx, sy       int
+				grabX, grabY float64
+			}
+			var handles []handle
+			b := selected.bounds(shape)
+			pad := penSize + 14
+			x0, y0 := toScreen(b.minX)-pad, toScreen(b.minY)-pad
+			x1, y1 := toScreen(b.maxX)+pad, toScreen(b.maxY)+pad
+			if len(selected) > 1 && !freehand {
+				handles = []handle{
+					{scalingGroup, x0, y0, b.minX, b.minY},
+					{scalingGroup, x1, y0, b.maxX, b.minY},
+					{scalingGroup, x0, y1, b.minX, b.maxY},
+					{scalingGroup, x1, y1, b.maxX, b.maxY},
+				}
+				rx, ry := (x0+x1)/2, y0-25
+				handles = append(handles, handle{
+					rotatingGroup, rx, ry, toCanvas(rx), toCanvas(ry),
+				})
 				if !hideControlPoints {
-					window.DrawText(strconv.Itoa(i), x, y, draw.DarkGreen)
+					window.DrawRect(x0, y0, x1-x0+1, y1-y0+1, draw.Blue)
+					window.DrawLine(rx, ry, rx, y0, draw.Blue)
+					for _, h := range handles {
+						window.FillRect(h.sx-4, h.sy-4, 9, 9, draw.Blue)
+					}
 				}
-			case line:
-				step := 1.0 / (canvasSize * math.Hypot(stroke.x1-stroke.x2, stroke.y1-stroke.y2))
-				curT := 0.0
-				for {
-					t := curT
-					curT += step
-					if t > 1 {
-						t = 1
-					}
-					x := toScreen(stroke.x1*t + (1-t)*stroke.x2)
-					y := toScreen(stroke.y1*t + (1-t)*stroke.y2)
-					drawDot(x-penSize/2, y-penSize/2, penSize, penSize, draw.Black)
-					if curT >= 1 {
+			}
+
+			if selectingArea {
+				mx, my := window.MousePosition()
+				ax, ay, bx, by := areaX, areaY, mx, my
+				if bx < ax {
+					ax, bx = bx, ax
+				}
+				if by < ay {
+					ay, by = by, ay
+				}
+				if window.IsMouseDown(draw.LeftButton) {
+					window.DrawRect(ax, ay, bx-ax+1, by-ay+1, draw.Gray)
+				} else {
+					for i := range shape {
+						for k := 0; k < shape[i].pointCount(); k++ {
+							x, y := shape[i].point(k)
+							sx, sy := toScreen(*x), toScreen(*y)
+							if sx >= ax && sy >= ay && sx <= bx && sy <= by {
+								selected[pointRef{stroke: i, point: k}] = true
+							}
+						}
+					}
+					selectingArea = false
+				}
+			}
+
+			var click *draw.MouseClick
+			if curX == nil && groupDrag == noGroupDrag && !selectingArea &&
+				!recording && window.IsMouseDown(draw.LeftButton) {
+				for _, c := range window.Clicks() {
+					if c.Button == draw.LeftButton {
+						c := c
+						click = &c
+					}
+				}
+			}
+			if click != nil {
+				mx, my := window.MousePosition()
+				grabbed := false
+				for _, h := range handles {
+					if click.X >= h.sx-5 && click.Y >= h.sy-5 &&
+						click.X <= h.sx+5 && click.Y <= h.sy+5 {
+						startGroupDrag(h.kind, h.grabX, h.grabY)
+						grabbed = true
 						break
 					}
 				}
-				if !hideControlPoints {
-					x := toScreen((stroke.x1 + stroke.x2) / 2)
-					y := toScreen((stroke.y1 + stroke.y2) / 2)
-					window.DrawText(strconv.Itoa(i), x, y, draw.DarkGreen)
+				hitStroke := -1
+				for i := range shape {
+					d := distanceToStroke(shape[i], toCanvas(click.X), toCanvas(click.Y))
+					if d*canvasSize <= float64(penSize)/2+4 {
+						hitStroke = i
+					}
 				}
-			case curve:
-				interp := func(t float64) (x, y float64) {
-					tt := 1.0 - t
-					x = tt*tt*stroke.x1 + 2*tt*t*stroke.x2 + t*t*stroke.x3
-					y = tt*tt*stroke.y1 + 2*tt*t*stroke.y2 + t*t*stroke.y3
-					return
+				inCanvas := click.X >= canvasMin && click.Y >= canvasMin &&
+					click.X < canvasMin+canvasSize && click.Y < canvasMin+canvasSize
+				switch {
+				case freehand:
+					if inCanvas {
+						recording = true
+						freehandPath = [][2]float64{{fromScreen(click.X), fromScreen(click.Y)}}
+					}
+				case grabbed:
+				case clickedPoint != nil:
+					ref := *clickedPoint
+					x, y := shape[ref.stroke].point(ref.point)
+					if shiftDown && selected[ref] {
+						delete(selected, ref)
+					} else if shiftDown {
+						selected[ref] = true
+					} else if selected[ref] && len(selected) > 1 {
+						startGroupDrag(movingGroup, *x, *y)
+					} else {
+						selected = selection{ref: true}
+						curX, curY = x, y
+						dragStart = currentState().copy()
+						curMouseDx = mx - toScreen(*x)
+						curMouseDy = my - toScreen(*y)
+					}
+				case !inCanvas:
+				case hitStroke != -1:
+					if shiftDown && selected.hasStroke(shape, hitStroke) {
+						for k := 0; k < shape[hitStroke].pointCount(); k++ {
+							delete(selected, pointRef{stroke: hitStroke, point: k})
+						}
+					} else if shiftDown {
+						selected.selectStroke(shape, hitStroke)
+					} else {
+						if !selected.hasStroke(shape, hitStroke) {
+							selected = make(selection)
+							selected.selectStroke(shape, hitStroke)
+						}
+						s := shape[hitStroke]
+						startGroupDrag(movingGroup, s.x1, s.y1)
+					}
+				default:
+					if !shiftDown {
+						selected = make(selection)
+					}
+					selectingArea = true
+					areaX, areaY = click.X, click.Y
 				}
-				step := 0.5 / (canvasSize * math.Hypot(stroke.x1-stroke.x3, stroke.y1-stroke.y3))
-				curT := 0.0
-				for {
-					t := curT
-					curT += step
-					if t > 1 {
-						t = 1
-					}
-					x, y := interp(t)
-					sx := toScreen(x)
-					sy := toScreen(y)
-					drawDot(sx-penSize/2, sy-penSize/2, penSize, penSize, draw.Black)
-					if curT >= 1 {
-						break
+			}
+		}
+
+		// record the freehand path and fit strokes to it when the mouse is
+		// released. The path's ends snap like dragged points so it joins other
+		// strokes, and its end snaps to its start to close it.
+		if recording {
+			mx, my := window.MousePosition()
+			p := [2]float64{fromScreen(mx), fromScreen(my)}
+			if p != freehandPath[len(freehandPath)-1] {
+				freehandPath = append(freehandPath, p)
+			}
+			for i := 1; i < len(freehandPath); i++ {
+				a, b := freehandPath[i-1], freehandPath[i]
+				window.DrawLine(toScreen(a[0]), toScreen(a[1]), toScreen(b[0]), toScreen(b[1]), draw.Gray)
+			}
+			if !window.IsMouseDown(draw.LeftButton) {
+				recording = false
+				fitted := fitStrokes(freehandPath, 3.0/canvasSize)
+				if len(fitted) > 0 {
+					first := &fitted[0]
+					last := &fitted[len(fitted)-1]
+					x1, y1 := first.point(0)
+					x2, y2 := last.point(last.pointCount() - 1)
+					if s, ok := snapAt(*x1, *y1, nil); ok {
+						*x1, *y1 = s.x, s.y
+					}
+					if s, ok := snapAt(*x2, *y2, nil); ok {
+						*x2, *y2 = s.x, s.y
+					} else if len(fitted) > 1 && useSnapping &&
+						math.Hypot(*x2-*x1, *y2-*y1) <= 8.0/canvasSize {
+						*x2, *y2 = *x1, *y1
 					}
+					remember()
+					shape = append(shape, fitted...)
 				}
-				if !hideControlPoints {
-					x := toScreen((stroke.x1 + stroke.x2) / 2)
-					y := toScreen((stroke.y1 + stroke.y2) / 2)
-					window.DrawText(strconv.Itoa(i), x, y, draw.DarkGreen)
+			}
+		}
+		if freehand {
+			window.DrawText("Freehand pen, F to switch it off", canvasMin+5, canvasMin+5, draw.Gray)
+		}
+
+		// the status line names the file that Ctrl+S writes, with a * if there
+		// are unsaved changes. Long paths are cut off at the front.
+		{
+			path := fontPath
+			if unsaved() {
+				path += " *"
+			}
+			text := path
+			if status != "" {
+				text += " - " + status
+			}
+			for w, _ := window.GetTextSize(text); w > canvasSize-10 && len(path) > 0; w, _ = window.GetTextSize(text) {
+				_, n := utf8.DecodeRuneInString(path)
+				path = path[n:]
+				text = "..." + path
+				if status != "" {
+					text += " - " + status
 				}
-			default:
-				panic("unknown stroke type")
 			}
+			_, th := window.GetTextSize(text)
+			window.DrawText(text, canvasMin+5, canvasMin+canvasSize-th-5, draw.DarkGray)
+		}
+
+		// show what the dragged point snapped to
+		if snapped != nil {
+			color := draw.Green
+			if snapped.kind == snapToStroke {
+				color = draw.DarkYellow
+			}
+			if snapped.kind == snapToGuide {
+				color = draw.Cyan
+			}
+			x, y := toScreen(snapped.x), toScreen(snapped.y)
+			radius := penSize/2 + 8
+			window.DrawEllipse(x-radius, y-radius, 2*radius+1, 2*radius+1, color)
+			window.DrawEllipse(x-radius-1, y-radius-1, 2*radius+3, 2*radius+3, color)
 		}
 
 		if !hideFrame {
@@ -507,6 +1817,18 @@ func main() {
 	}))
 }
 
+// penShape is the form of the pen's tip that the strokes are drawn with.
+type penShape int
+
+const (
+	rectangular penShape = iota
+	circular
+)
+
+// baseLine is the y coordinate of the line that letters sit on. Coordinates of
+// a letter go from 0 to 1, with y pointing down.
+const baseLine = 2.0 / 3.0
+
 func check(err error) {
 	if err != nil {
 		panic(err)
@@ -518,11 +1840,43 @@ type appSettings struct {
 	PenShape          int
 	PenSize           int
 	UseGrid           bool
+	DisableSnapping   bool
 	GridSize          float64
 	HideControlPoints bool
 	HideBaseLine      bool
 	HideFrame         bool
 	HideGrid          bool
+	CoverageTarget    string
+	RecentFiles       []string
+	References        map[rune]referenceImage
+	PreviewText       string
+	PreviewSize       int
+	PreviewPen        int
+}
+
+// absolutePath returns the path as an absolute one, or unchanged if that is
+// not possible.
+func absolutePath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// maxRecentFiles is how many font files the editor remembers.
+const maxRecentFiles = 10
+
+// addRecentFile puts the path at the front of the recently used files, as an
+// absolute path so it works from any working directory.
+func addRecentFile(recent []string, path string) []string {
+	path = absolutePath(path)
+	list := []string{path}
+	for _, p := range recent {
+		if p != path && len(list) < maxRecentFiles {
+			list = append(list, p)
+		}
+	}
+	return list
 }
 
 func saveAppSettings(s appSettings, path string) error {
@@ -543,17 +1897,21 @@ func loadAppSettings(path string) (appSettings, error) {
 	return s, err
 }
 
-const exportFileVersion = 1
+const exportFileVersion = 3
 
 // exportFile's file format (little-endian encoding is used):
 //
 // 4 byte     ASCII "STRK" or 1263686739 as integer
 // 4 byte     file version
+// 	font info (since version 3):
+// uint32     length of the family name in bytes, followed by it in UTF-8
+// uint32     length of the style name in bytes, followed by it in UTF-8
 // uint32     length of the following table in bytes
 // 	letter table, list of entries:
-// uint32     unicode character
+// uint32     unicode character, 0xFFFFFFFF for the notdef glyph
 // uint32     offset into the data section where the shape is defined
 // uint32     number of strokes for this character
+// float32    advance, 0 if not defined (since version 2)
 // 	data section, list of shapes:
 // 6 float32  x1, y1, x2, y2, x3, y3:
 //            these describe a bezier curve from x1,y1 to x3,y3 with control
@@ -562,6 +1920,10 @@ const exportFileVersion = 1
 //            If the last two points are the same, points 1 and 2 describe a
 //            straight line.
 func exportFile(list letters, path string) error {
+	return exportFileWithInfo(list, fontInfo{}, path)
+}
+
+func exportFileWithInfo(list letters, info fontInfo, path string) error {
 	list = simplify(list)
 
 	var buf bytes.Buffer
@@ -572,14 +1934,21 @@ func exportFile(list letters, path string) error {
 	w.WriteString("STRK")
 	binary.Write(w, enc, uint32(exportFileVersion))
 
+	// font info
+	for _, s := range []string{info.family, info.style} {
+		binary.Write(w, enc, uint32(len(s)))
+		w.WriteString(s)
+	}
+
 	// table with offsets for letter shapes
-	binary.Write(w, enc, uint32(3*4*len(list))) // table length in bytes
+	binary.Write(w, enc, uint32(4*4*len(list))) // table length in bytes
 	sort.Sort(list)
 	var offset uint32
 	for _, l := range list {
 		binary.Write(w, enc, uint32(l.r))
 		binary.Write(w, enc, offset)
 		binary.Write(w, enc, uint32(len(l.shape)))
+		binary.Write(w, enc, float32(l.advance))
 		offset += uint32(3 * 2 * 4 * len(l.shape))
 	}
 
@@ -620,8 +1989,12 @@ func exportFile(list letters, path string) error {
 func simplify(list letters) letters {
 	var out letters
 	for _, l := range list {
-		if len(l.shape) > 0 {
-			out = append(out, letter{r: l.r, shape: linearize(l.shape)})
+		if hasGlyph(l) {
+			out = append(out, letter{
+				r:       l.r,
+				shape:   linearize(l.shape),
+				advance: l.advance,
+			})
 		}
 	}
 	return out
@@ -788,40 +2161,93 @@ func containsEdge(edges []*edge, e *edge) bool {
 }
 
 func importFile(path string) (letters, error) {
+	list, _, err := importFileWithInfo(path)
+	return list, err
+}
+
+func importFileWithInfo(path string) (letters, fontInfo, error) {
+	var info fontInfo
 	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		return nil, err
+		return nil, info, err
 	}
-	r := &errReader{Reader: bytes.NewReader(data)}
+	br := bytes.NewReader(data)
+	r := &errReader{Reader: br}
 	enc := binary.LittleEndian
 
 	// check file magic and version
 	var magic [4]byte
 	binary.Read(r, enc, &magic)
 	if string(magic[:]) != "STRK" {
-		return nil, errors.New("STRK expected as magic number at file start")
+		return nil, info, errors.New("STRK expected as magic number at file start")
 	}
 
 	var version uint32
 	binary.Read(r, enc, &version)
-	if version != exportFileVersion {
-		return nil, errors.New("wrong file version")
+	if version < 1 || version > exportFileVersion {
+		return nil, info, errors.New("wrong file version")
+	}
+
+	// read font info
+	if version >= 3 {
+		for _, s := range []*string{&info.family, &info.style} {
+			var n uint32
+			binary.Read(r, enc, &n)
+			if n > uint32(len(data)) {
+				return nil, info, errors.New("font info is too long")
+			}
+			b := make([]byte, n)
+			binary.Read(r, enc, b)
+			*s = string(b)
+		}
 	}
 
 	// read character-to-stroke-offset table
 	var headerSize uint32
 	binary.Read(r, enc, &headerSize)
 	type entry struct {
-		Char   uint32
-		Offset uint32
-		N      uint32
+		Char    uint32
+		Offset  uint32
+		N       uint32
+		Advance float32
+	}
+	entrySize := uint32(16)
+	if version == 1 {
+		entrySize = 12
+	}
+	if headerSize > uint32(br.Len()) {
+		return nil, info, errors.New("offset table is larger than the file")
+	}
+	table := make([]entry, headerSize/entrySize)
+	for i := range table {
+		binary.Read(r, enc, &table[i].Char)
+		binary.Read(r, enc, &table[i].Offset)
+		binary.Read(r, enc, &table[i].N)
+		if version >= 2 {
+			binary.Read(r, enc, &table[i].Advance)
+		}
 	}
-	table := make([]entry, headerSize/12)
-	binary.Read(r, enc, &table)
 
-	// read shapes
+	// read shapes, they are stored in the order of the table, one after
+	// another, so every offset must be where the previous shape ends
+	const strokeSize = 6 * 4
+	strokeData := uint64(br.Len())
+	var offset uint64
 	var list letters
 	for _, e := range table {
+		if uint64(e.Offset) != offset {
+			return nil, info, fmt.Errorf(
+				"letter %s: strokes at offset %d, expected %d",
+				letterName(rune(e.Char)), e.Offset, offset,
+			)
+		}
+		offset += uint64(e.N) * strokeSize
+		if offset > strokeData {
+			return nil, info, fmt.Errorf(
+				"letter %s: %d strokes do not fit into the file",
+				letterName(rune(e.Char)), e.N,
+			)
+		}
 		shape := make(strokes, e.N)
 		for i := range shape {
 			var x1, y1, x2, y2, x3, y3 float32
@@ -847,15 +2273,21 @@ func importFile(path string) (letters, error) {
 			shape[i].y3 = float64(y3)
 		}
 		list = append(list, letter{
-			r:     rune(e.Char),
-			shape: shape,
+			r:       rune(e.Char),
+			shape:   shape,
+			advance: float64(e.Advance),
 		})
 	}
 
-	if r.err != nil && r.err != io.EOF {
-		return nil, err
+	// reading stops with io.EOF only if the file is shorter than its tables
+	// say, complete files are read without reaching it
+	if r.err == io.EOF {
+		return nil, info, io.ErrUnexpectedEOF
+	}
+	if r.err != nil {
+		return nil, info, r.err
 	}
-	return list, nil
+	return list, info, nil
 }
 
 type errReader struct {
@@ -872,11 +2304,24 @@ func (r *errReader) Read(p []byte) (n int, err error) {
 	return
 }
 
+// fontInfo is the font's metadata that is stored along with the letters.
+type fontInfo struct {
+	// family is the common name of all styles of a font, e.g. "Plotter".
+	family string
+	// style tells the variants of a family apart, e.g. "Regular", "Oblique"
+	// or "Condensed".
+	style string
+}
+
 type letters []letter
 
 type letter struct {
 	r     rune
 	shape strokes
+	// advance is the distance from the left of this letter's canvas to the
+	// left of the next letter's canvas. It is 0 if the font does not define
+	// it, see font.advance.
+	advance float64
 }
 
 func (x letters) Len() int           { return len(x) }
@@ -0,0 +1,184 @@
+package main
+
+import "math"
+
+// fitStrokes replaces a freehand path, e.g. as drawn with the mouse, by few
+// lines and quadratic curves, none of which is farther than tolerance away
+// from the path. If one stroke does not fit, the path is split at the point
+// that is farthest from it and both halves are fitted the same way. Splitting
+// can leave neighbors that fit as one stroke, these are joined afterwards. The strokes are joined end to end, each one
+// starts exactly where the one before it ends, so linearize keeps them as one
+// continuous path. A path that does not go anywhere becomes a dot.
+func fitStrokes(path [][2]float64, tolerance float64) strokes {
+	// points that are much closer together than the tolerance only add noise
+	var points [][2]float64
+	for _, p := range path {
+		if len(points) == 0 || pointDistance(points[len(points)-1], p) > tolerance/10 {
+			points = append(points, p)
+		}
+	}
+	if len(points) == 0 {
+		return nil
+	}
+	if len(points) == 1 {
+		return strokes{{typ: dot, x1: points[0][0], y1: points[0][1]}}
+	}
+
+	// pieces are the ranges of points, from first to last, that the strokes
+	// are fitted to. Neighbors share a point so the strokes stay joined.
+	type piece struct {
+		first, last int
+		stroke      stroke
+	}
+	var pieces []piece
+	var split func(first, last int)
+	split = func(first, last int) {
+		s, worst := fitSegment(points[first:last+1], tolerance)
+		if worst < 0 {
+			pieces = append(pieces, piece{first, last, s})
+			return
+		}
+		split(first, first+worst)
+		split(first+worst, last)
+	}
+	split(0, len(points)-1)
+
+	for i := 0; i+1 < len(pieces); {
+		a, b := pieces[i], pieces[i+1]
+		if s, worst := fitSegment(points[a.first:b.last+1], tolerance); worst < 0 {
+			pieces[i] = piece{a.first, b.last, s}
+			pieces = append(pieces[:i+1], pieces[i+2:]...)
+			if i > 0 {
+				i--
+			}
+		} else {
+			i++
+		}
+	}
+
+	fitted := make(strokes, len(pieces))
+	for i, p := range pieces {
+		fitted[i] = p.stroke
+	}
+	return fitted
+}
+
+func pointDistance(a, b [2]float64) float64 {
+	return math.Hypot(b[0]-a[0], b[1]-a[1])
+}
+
+func lineStroke(a, b [2]float64) stroke {
+	return stroke{typ: line, x1: a[0], y1: a[1], x2: b[0], y2: b[1]}
+}
+
+// fitSegment finds a line or, if that does not fit, a quadratic curve from the
+// first to the last point that passes all points within the tolerance. If
+// neither fits, it returns the index of the point where the path should be
+// split, otherwise -1. That is the inner point farthest from the curve, or the
+// middle one if the curve strays from the path between points that it
+// passes.
+func fitSegment(points [][2]float64, tolerance float64) (stroke, int) {
+	a, b := points[0], points[len(points)-1]
+	l := lineStroke(a, b)
+	if strokeFits(l, points, tolerance) {
+		return l, -1
+	}
+	middle := len(points) / 2
+
+	// Each point gets a parameter t along the curve, at first by its distance
+	// along the path. The control point is then found by least squares and
+	// the parameters are improved to the closest points on that curve.
+	t := make([]float64, len(points))
+	length := 0.0
+	for i := 1; i < len(points); i++ {
+		length += pointDistance(points[i-1], points[i])
+		t[i] = length
+	}
+	if length == 0 {
+		return l, middle
+	}
+	for i := range t {
+		t[i] /= length
+	}
+	var c [2]float64
+	for iteration := 0; iteration < 4; iteration++ {
+		var ok bool
+		c, ok = leastSquaresControl(points, t)
+		if !ok {
+			return l, middle
+		}
+		for i := 1; i < len(points)-1; i++ {
+			t[i] = closestParameter(a, c, b, points[i], t[i])
+		}
+	}
+	s := stroke{typ: curve, x1: a[0], y1: a[1], x2: c[0], y2: c[1], x3: b[0], y3: b[1]}
+	if strokeFits(s, points, tolerance) {
+		return s, -1
+	}
+	drawn := s.flatten(tolerance / 10)
+	worst, worstDistance := middle, tolerance
+	for i := 1; i < len(points)-1; i++ {
+		if _, _, d := closestOnPolyline(drawn, points[i][0], points[i][1]); d > worstDistance {
+			worst, worstDistance = i, d
+		}
+	}
+	return s, worst
+}
+
+// leastSquaresControl returns the control point of the quadratic curve from
+// the first to the last point that passes the points at their parameters t
+// with the least squared error.
+func leastSquaresControl(points [][2]float64, t []float64) ([2]float64, bool) {
+	a, b := points[0], points[len(points)-1]
+	var sum [2]float64
+	weights := 0.0
+	for i, p := range points {
+		u := t[i]
+		w := 2 * u * (1 - u)
+		for k := 0; k < 2; k++ {
+			sum[k] += w * (p[k] - (1-u)*(1-u)*a[k] - u*u*b[k])
+		}
+		weights += w * w
+	}
+	if weights == 0 {
+		return [2]float64{}, false
+	}
+	return [2]float64{sum[0] / weights, sum[1] / weights}, true
+}
+
+// closestParameter improves the parameter t of the quadratic curve a,c,b so
+// that the curve's point at t is closer to p, with a few Newton steps.
+func closestParameter(a, c, b, p [2]float64, t float64) float64 {
+	for step := 0; step < 3; step++ {
+		var f, df float64
+		for k := 0; k < 2; k++ {
+			pos := (1-t)*(1-t)*a[k] + 2*t*(1-t)*c[k] + t*t*b[k] - p[k]
+			d1 := 2*(1-t)*(c[k]-a[k]) + 2*t*(b[k]-c[k])
+			d2 := 2 * (b[k] - 2*c[k] + a[k])
+			f += pos * d1
+			df += d1*d1 + pos*d2
+		}
+		if df == 0 {
+			break
+		}
+		t = math.Max(0, math.Min(1, t-f/df))
+	}
+	return t
+}
+
+// strokeFits tells if all points are within the tolerance of the stroke and
+// the stroke does not stray farther than that from the path between them.
+func strokeFits(s stroke, points [][2]float64, tolerance float64) bool {
+	drawn := s.flatten(tolerance / 10)
+	for _, p := range points {
+		if _, _, d := closestOnPolyline(drawn, p[0], p[1]); d > tolerance {
+			return false
+		}
+	}
+	for _, p := range drawn {
+		if _, _, d := closestOnPolyline(points, p[0], p[1]); d > tolerance {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFitStrokes(t *testing.T) {
+	const tolerance = 0.004
+	// pixel rounds the points to the editor's canvas pixels, like the mouse
+	pixel := func(x, y float64) [2]float64 {
+		return [2]float64{math.Round(x*780) / 780, math.Round(y*780) / 780}
+	}
+	var straight, corner, circle [][2]float64
+	for i := 0; i <= 50; i++ {
+		straight = append(straight, pixel(0.1+float64(i)*0.01, 0.2))
+	}
+	for i := 0; i <= 30; i++ {
+		corner = append(corner, pixel(0.1, 0.1+float64(i)*0.01))
+	}
+	for i := 1; i <= 30; i++ {
+		corner = append(corner, pixel(0.1+float64(i)*0.01, 0.4))
+	}
+	for i := 0; i <= 200; i++ {
+		a := 2 * math.Pi * float64(i) / 200
+		circle = append(circle, pixel(0.5+0.3*math.Cos(a), 0.5-0.3*math.Sin(a)))
+	}
+
+	tests := []struct {
+		name      string
+		path      [][2]float64
+		minCount  int
+		maxCount  int
+		onlyLines bool
+	}{
+		{"straight", straight, 1, 1, true},
+		{"corner", corner, 2, 2, true},
+		{"circle", circle, 4, 6, false},
+	}
+	for _, test := range tests {
+		s := fitStrokes(test.path, tolerance)
+		if len(s) < test.minCount || len(s) > test.maxCount {
+			t.Errorf("%s: %d strokes, want %d to %d", test.name, len(s), test.minCount, test.maxCount)
+			continue
+		}
+		for i := range s {
+			if test.onlyLines && s[i].typ != line {
+				t.Errorf("%s: stroke %d is not a line", test.name, i)
+			}
+			if i > 0 && s[i-1].end() != s[i].start() {
+				t.Errorf("%s: stroke %d does not start where the one before ends", test.name, i)
+			}
+		}
+		var drawn [][2]float64
+		for _, stroke := range s {
+			drawn = append(drawn, stroke.flatten(tolerance/10)...)
+		}
+		for _, p := range test.path {
+			if _, _, d := closestOnPolyline(drawn, p[0], p[1]); d > tolerance {
+				t.Errorf("%s: point %v is %g away from the strokes", test.name, p, d)
+				break
+			}
+		}
+	}
+}
+
+func TestFitStrokesWithoutMovementIsDot(t *testing.T) {
+	s := fitStrokes([][2]float64{{0.3, 0.3}, {0.3, 0.3}}, 0.004)
+	if len(s) != 1 || s[0].typ != dot {
+		t.Errorf("want one dot but have %v", s)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import "math"
+
+// flatten returns the stroke as a polyline that is nowhere farther than
+// tolerance away from the actual stroke. A dot is a single point, a line its
+// two end points and curves are split in halves until each half is flat
+// enough.
+//
+// Everything that needs the strokes' geometry, be it for drawing or
+// exporting, should get it from here.
+func (s *stroke) flatten(tolerance float64) [][2]float64 {
+	switch s.typ {
+	case dot:
+		return [][2]float64{{s.x1, s.y1}}
+	case line:
+		return [][2]float64{{s.x1, s.y1}, {s.x2, s.y2}}
+	case curve:
+		points := [][2]float64{{s.x1, s.y1}}
+		return flattenCurve(
+			points,
+			[2]float64{s.x1, s.y1},
+			[2]float64{s.x2, s.y2},
+			[2]float64{s.x3, s.y3},
+			tolerance,
+			0,
+		)
+	default:
+		panic("unknown stroke type")
+	}
+}
+
+// maxFlattenDepth limits the number of halvings to 2^maxFlattenDepth pieces
+// per curve, in case of a tolerance of 0 or NaN coordinates.
+const maxFlattenDepth = 16
+
+// flattenCurve appends the quadratic bezier curve from a to c with control
+// point b to the points, leaving out a which is expected to be there already.
+func flattenCurve(points [][2]float64, a, b, c [2]float64, tolerance float64, depth int) [][2]float64 {
+	// The difference between the curve and the line from a to c at parameter
+	// t is t*(1-t)*(2b-a-c), which is largest at t = 0.5. Unlike the distance
+	// to the line between a and c, this also works for closed curves where a
+	// and c are the same.
+	deviation := math.Hypot(2*b[0]-a[0]-c[0], 2*b[1]-a[1]-c[1]) / 4
+	if deviation <= tolerance || depth >= maxFlattenDepth {
+		return append(points, c)
+	}
+	mid := func(p, q [2]float64) [2]float64 {
+		return [2]float64{(p[0] + q[0]) / 2, (p[1] + q[1]) / 2}
+	}
+	ab, bc := mid(a, b), mid(b, c)
+	center := mid(ab, bc)
+	points = flattenCurve(points, a, ab, center, tolerance, depth+1)
+	return flattenCurve(points, center, bc, c, tolerance, depth+1)
+}
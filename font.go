@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// font is a set of letters that can be looked up by their rune, which is what
+// is needed to set text.
+type font struct {
+	glyphs map[rune]letter
+	// kerning moves the second letter of a pair closer to (negative) or
+	// farther away from (positive) the first, in canvas units. It is optional.
+	kerning map[[2]rune]float64
+	// fallback is searched for letters that are not in this font, e.g. a font
+	// of symbols. It can have a fallback itself. It is optional.
+	fallback *font
+}
+
+// notdef is the rune of the glyph that is drawn in place of letters that are
+// missing from a font, usually a box. It is not a valid Unicode character so
+// it cannot clash with a real letter. Font files store it as 0xFFFFFFFF.
+const notdef rune = -1
+
+// letterName returns r quoted like a Go character literal, or .notdef.
+func letterName(r rune) string {
+	if r == notdef {
+		return ".notdef"
+	}
+	return fmt.Sprintf("%q", r)
+}
+
+// defaultNotdef is drawn for missing letters if none of the fonts in the
+// fallback chain has its own notdef glyph. It is a box with a cross from the
+// base line to the top of capital letters.
+var defaultNotdef = letter{
+	r: notdef,
+	shape: strokes{
+		{typ: line, x1: 0.05, y1: 0.1, x2: 0.45, y2: 0.1},
+		{typ: line, x1: 0.45, y1: 0.1, x2: 0.45, y2: baseLine},
+		{typ: line, x1: 0.45, y1: baseLine, x2: 0.05, y2: baseLine},
+		{typ: line, x1: 0.05, y1: baseLine, x2: 0.05, y2: 0.1},
+		{typ: line, x1: 0.05, y1: 0.1, x2: 0.45, y2: baseLine},
+		{typ: line, x1: 0.45, y1: 0.1, x2: 0.05, y2: baseLine},
+	},
+	advance: 0.5,
+}
+
+func newFont(list letters) *font {
+	f := &font{glyphs: make(map[rune]letter)}
+	for _, l := range list {
+		f.glyphs[l.r] = l
+	}
+	return f
+}
+
+const (
+	// letterSpacing is the gap between the right-most point of a letter and
+	// the start of the next letter's canvas.
+	letterSpacing = 0.1
+	// emptyAdvance is the advance of letters without strokes, e.g. the space.
+	emptyAdvance = 0.3
+)
+
+// glyph returns the letter that is drawn for r. It comes from the first font
+// in the fallback chain that has r. If none has it, r is replaced by the first
+// notdef glyph in the chain or by defaultNotdef. White space is never replaced
+// since it is not drawn anyway, it is returned without strokes instead.
+func (f *font) glyph(r rune) letter {
+	for g := f; g != nil; g = g.fallback {
+		if l, ok := g.glyphs[r]; ok && hasGlyph(l) {
+			return l
+		}
+	}
+	if unicode.IsSpace(r) {
+		return letter{r: r}
+	}
+	for g := f; g != nil; g = g.fallback {
+		if l, ok := g.glyphs[notdef]; ok && hasGlyph(l) {
+			return l
+		}
+	}
+	return defaultNotdef
+}
+
+// advance returns the distance from the left of r's canvas to the left of the
+// next letter's canvas. If the font does not define it, it is the right-most
+// point of the letter's strokes plus the letter spacing.
+func (f *font) advance(r rune) float64 {
+	l := f.glyph(r)
+	if l.advance != 0 {
+		return l.advance
+	}
+	if len(l.shape) == 0 {
+		return emptyAdvance
+	}
+	return l.shape.bounds().maxX + letterSpacing
+}
+
+// kern returns the kerning between two adjacent letters, 0 if there is none.
+// The first font in the fallback chain that has a kerning for the pair is
+// used.
+func (f *font) kern(a, b rune) float64 {
+	for g := f; g != nil; g = g.fallback {
+		if k, ok := g.kerning[[2]rune{a, b}]; ok {
+			return k
+		}
+	}
+	return 0
+}
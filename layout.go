@@ -0,0 +1,221 @@
+package main
+
+import (
+	"math"
+	"strings"
+)
+
+type textAlign int
+
+const (
+	alignLeft textAlign = iota
+	alignCenter
+	alignRight
+	// alignJustify stretches the spaces in a line so it fills the box's
+	// width. The last line of a paragraph is aligned left.
+	alignJustify
+)
+
+type layoutOptions struct {
+	// size is the height of a letter's canvas in output units. It defaults to
+	// 1 if it is not positive, which lays the text out in canvas units.
+	size float64
+	// lineHeight is the distance between two base lines, in canvas heights.
+	// It defaults to 1 if it is 0.
+	lineHeight float64
+	align      textAlign
+}
+
+// layoutBox is an axis-aligned rectangle with y pointing down.
+type layoutBox struct {
+	x, y, w, h float64
+}
+
+// placedLetter is a letter's shape with its canvas scaled to size and its
+// top-left corner moved to x,y.
+type placedLetter struct {
+	r     rune
+	x, y  float64
+	size  float64
+	shape strokes
+}
+
+// layoutText sets the text in the box, starting at the top. The first line's
+// canvas is at the box's top so its base line is baseLine*size below it. Lines
+// are wrapped at spaces to fit the box's width, or inside words if these do not
+// fit on a line by themselves. A box width of 0 means no wrapping. Text that
+// does not fit the box's height goes on below it. Letters that are not in the
+// font or its fallbacks are drawn as notdef glyphs, see font.glyph.
+func layoutText(f *font, text string, box layoutBox, opt layoutOptions) []placedLetter {
+	opt.size = letterSize(opt)
+	var placed []placedLetter
+	lines, width := f.wrap(text, box.w/opt.size)
+	if box.w > 0 {
+		width = box.w / opt.size
+	}
+	for i, line := range lines {
+		x := 0.0
+		spacing := 0.0
+		switch opt.align {
+		case alignCenter:
+			x = (width - line.width) / 2
+		case alignRight:
+			x = width - line.width
+		case alignJustify:
+			if !line.last && line.spaces > 0 {
+				spacing = (width - line.width) / float64(line.spaces)
+			}
+		}
+		y := float64(i) * lineHeight(opt)
+		for j, r := range line.runes {
+			if j > 0 {
+				x += f.kern(line.runes[j-1], r)
+			}
+			if l := f.glyph(r); len(l.shape) > 0 {
+				placed = append(placed, placedLetter{
+					r:     r,
+					x:     box.x + x*opt.size,
+					y:     box.y + y*opt.size,
+					size:  opt.size,
+					shape: l.shape,
+				})
+			}
+			x += f.advance(r)
+			if r == ' ' {
+				x += spacing
+			}
+		}
+	}
+	return placed
+}
+
+// measureText returns the box that layoutText fills with the text, without
+// the need to lay out the strokes. Its height is the number of lines times
+// the line height.
+func measureText(f *font, text string, box layoutBox, opt layoutOptions) layoutBox {
+	opt.size = letterSize(opt)
+	lines, width := f.wrap(text, box.w/opt.size)
+	left := 0.0
+	if box.w > 0 && opt.align != alignLeft {
+		switch opt.align {
+		case alignCenter:
+			left = (box.w/opt.size - width) / 2
+		case alignRight:
+			left = box.w/opt.size - width
+		case alignJustify:
+			// All lines but the last of each paragraph are stretched to fill
+			// the box, unless there are no spaces to stretch.
+			for _, line := range lines {
+				if !line.last && line.spaces > 0 {
+					width = box.w / opt.size
+				}
+			}
+		}
+	}
+	return layoutBox{
+		x: box.x + left*opt.size,
+		y: box.y,
+		w: width * opt.size,
+		h: float64(len(lines)) * lineHeight(opt) * opt.size,
+	}
+}
+
+func lineHeight(opt layoutOptions) float64 {
+	if opt.lineHeight == 0 {
+		return 1
+	}
+	return opt.lineHeight
+}
+
+func letterSize(opt layoutOptions) float64 {
+	if opt.size <= 0 {
+		return 1
+	}
+	return opt.size
+}
+
+type textLine struct {
+	runes []rune
+	// width is the sum of all advances and kerning, in canvas units.
+	width float64
+	// spaces is the number of spaces between the words in the line.
+	spaces int
+	// last is true for the last line of a paragraph.
+	last bool
+}
+
+// wrap breaks the text into lines no wider than maxWidth, which is unlimited
+// if it is 0. Explicit line breaks always start a new line. The spaces at
+// which lines are wrapped are removed. It also returns the widest line's
+// width.
+func (f *font) wrap(text string, maxWidth float64) ([]textLine, float64) {
+	width := func(runes []rune) float64 {
+		w := 0.0
+		for i, r := range runes {
+			if i > 0 {
+				w += f.kern(runes[i-1], r)
+			}
+			w += f.advance(r)
+		}
+		return w
+	}
+	fits := func(runes []rune) bool {
+		return maxWidth <= 0 || width(runes) <= maxWidth
+	}
+
+	var lines []textLine
+	for _, paragraph := range strings.Split(text, "\n") {
+		var line []rune
+		// lineStarted is true if line has at least one, possibly empty, word
+		lineStarted := false
+		words := strings.Split(paragraph, " ")
+		for i := 0; i < len(words); i++ {
+			word := []rune(words[i])
+			candidate := word
+			if lineStarted {
+				candidate = append(append(append([]rune(nil), line...), ' '), word...)
+			}
+			if fits(candidate) {
+				line = candidate
+				lineStarted = true
+				continue
+			}
+			if lineStarted {
+				// the word goes onto the next line
+				lines = append(lines, textLine{runes: line})
+				line = nil
+				lineStarted = false
+				if fits(word) {
+					line = word
+					lineStarted = true
+					continue
+				}
+			}
+			// the word is too long for a line by itself so it is split, with
+			// at least one letter per line. A single letter cannot be split,
+			// it starts the line like a word that fits.
+			n := 1
+			for n < len(word) && fits(word[:n+1]) {
+				n++
+			}
+			if n == len(word) {
+				line = word
+				lineStarted = true
+				continue
+			}
+			lines = append(lines, textLine{runes: word[:n]})
+			words[i] = string(word[n:])
+			i--
+		}
+		lines = append(lines, textLine{runes: line, last: true})
+	}
+
+	maxLineWidth := 0.0
+	for i := range lines {
+		l := &lines[i]
+		l.width = width(l.runes)
+		l.spaces = strings.Count(string(l.runes), " ")
+		maxLineWidth = math.Max(maxLineWidth, l.width)
+	}
+	return lines, maxLineWidth
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// layoutTestFont has an 'a' that is half a canvas wide and a space that is a
+// quarter wide.
+func layoutTestFont() *font {
+	return newFont(letters{
+		{r: 'a', advance: 0.5, shape: strokes{{typ: line, x1: 0.1, y1: 0.6, x2: 0.4, y2: 0.6}}},
+		{r: ' ', advance: 0.25},
+	})
+}
+
+func TestWrap(t *testing.T) {
+	type line struct {
+		text string
+		last bool
+	}
+	tests := []struct {
+		name     string
+		text     string
+		maxWidth float64
+		want     []line
+	}{
+		{"no wrapping", "aa aa", 0, []line{{"aa aa", true}}},
+		{"wrap at space", "aa aa", 1.5, []line{{"aa", false}, {"aa", true}}},
+		{"line breaks", "a\n\na", 0, []line{{"a", true}, {"", true}, {"a", true}}},
+		{"word longer than the line", "aaaaa", 1, []line{{"aa", false}, {"aa", false}, {"a", true}}},
+		{"letter wider than the line", "aa", 0.3, []line{{"a", false}, {"a", true}}},
+		{"long word after short one", "a aaa", 1, []line{{"a", false}, {"aa", false}, {"a", true}}},
+		{"only spaces", "   ", 1, []line{{"   ", true}}},
+		{"empty", "", 1, []line{{"", true}}},
+	}
+	for _, test := range tests {
+		lines, _ := layoutTestFont().wrap(test.text, test.maxWidth)
+		var have []line
+		for _, l := range lines {
+			have = append(have, line{string(l.runes), l.last})
+		}
+		if !reflect.DeepEqual(have, test.want) {
+			t.Errorf("%s: want %v but have %v", test.name, test.want, have)
+		}
+	}
+}
+
+func TestLayoutText(t *testing.T) {
+	type position struct{ x, y float64 }
+	tests := []struct {
+		name  string
+		text  string
+		box   layoutBox
+		align textAlign
+		want  []position
+	}{
+		{
+			"left",
+			"aa aa aa", layoutBox{w: 2.5}, alignLeft,
+			[]position{{0, 0}, {0.5, 0}, {1.25, 0}, {1.75, 0}, {0, 1}, {0.5, 1}},
+		},
+		{
+			"right",
+			"aa", layoutBox{x: 1, y: 2, w: 2}, alignRight,
+			[]position{{2, 2}, {2.5, 2}},
+		},
+		{
+			"justify stretches all but the last line",
+			"aa aa aa", layoutBox{w: 2.5}, alignJustify,
+			[]position{{0, 0}, {0.5, 0}, {1.5, 0}, {2, 0}, {0, 1}, {0.5, 1}},
+		},
+		{
+			"justify leaves a last line with spaces alone",
+			"aa aa aa aa", layoutBox{w: 2.5}, alignJustify,
+			[]position{
+				{0, 0}, {0.5, 0}, {1.5, 0}, {2, 0},
+				{0, 1}, {0.5, 1}, {1.25, 1}, {1.75, 1},
+			},
+		},
+	}
+	for _, test := range tests {
+		placed := layoutText(layoutTestFont(), test.text, test.box, layoutOptions{
+			size:  1,
+			align: test.align,
+		})
+		var have []position
+		for _, p := range placed {
+			have = append(have, position{p.x, p.y})
+		}
+		if !reflect.DeepEqual(have, test.want) {
+			t.Errorf("%s: want %v but have %v", test.name, test.want, have)
+		}
+	}
+}
+
+func TestLayoutDefaultsToSizeOne(t *testing.T) {
+	f := layoutTestFont()
+	box := layoutBox{w: 1.5}
+	want := layoutText(f, "aa aa", box, layoutOptions{size: 1})
+	for _, size := range []float64{0, -1} {
+		opt := layoutOptions{size: size}
+		if have := layoutText(f, "aa aa", box, opt); !reflect.DeepEqual(have, want) {
+			t.Errorf("size %g: want %v but have %v", size, want, have)
+		}
+		if m := measureText(f, "aa aa", box, opt); m.w != 1 || m.h != 2 {
+			t.Errorf("size %g: measured %v", size, m)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// mergePolicy decides which letter to take if several fonts that are merged
+// define the same rune differently.
+type mergePolicy int
+
+const (
+	keepFirst mergePolicy = iota
+	keepLast
+	// keepMostStrokes takes the letter with the most strokes, of these the
+	// first one.
+	keepMostStrokes
+	// failOnConflict makes mergeFonts fail and list all conflicts.
+	failOnConflict
+)
+
+var mergePolicyNames = []string{"first", "last", "most", "fail"}
+
+func (p mergePolicy) String() string {
+	return mergePolicyNames[p]
+}
+
+func findMergePolicy(name string) (mergePolicy, bool) {
+	for i, n := range mergePolicyNames {
+		if n == name {
+			return mergePolicy(i), true
+		}
+	}
+	return 0, false
+}
+
+// mergeReport tells where each letter of a merged font comes from.
+type mergeReport struct {
+	sources []string
+	policy  mergePolicy
+	// origin is the index of the source of every letter in the result.
+	origin map[rune]int
+	// conflicts lists the indices of all sources that define a rune, for
+	// runes that are not defined the same in all of them.
+	conflicts map[rune][]int
+}
+
+// mergeFonts combines the letters of all sources into one font. The names of
+// the sources are used in the report and errors. Letters that are the same in
+// several sources are not conflicts, they are taken from the first source.
+func mergeFonts(names []string, sources []letters, policy mergePolicy) (letters, mergeReport, error) {
+	report := mergeReport{
+		sources:   names,
+		policy:    policy,
+		origin:    make(map[rune]int),
+		conflicts: make(map[rune][]int),
+	}
+	merged := make(map[rune]letter)
+	defined := make(map[rune][]int)
+	conflicting := make(map[rune]bool)
+	fonts := make([]*font, len(sources))
+	for i, list := range sources {
+		fonts[i] = newFont(list)
+	}
+	for i, list := range sources {
+		for _, l := range list {
+			if !hasGlyph(l) {
+				continue
+			}
+			defined[l.r] = append(defined[l.r], i)
+			prev, ok := merged[l.r]
+			if !ok {
+				merged[l.r] = l
+				report.origin[l.r] = i
+				continue
+			}
+			// Advances are compared as they are used, estimated or not, and
+			// with the precision of font files.
+			prevAdvance := fonts[report.origin[l.r]].advance(l.r)
+			if math.Abs(prevAdvance-fonts[i].advance(l.r)) <= 1e-6 &&
+				sameStrokes(prev.shape, l.shape) {
+				continue
+			}
+			conflicting[l.r] = true
+			if policy == keepLast ||
+				policy == keepMostStrokes && len(l.shape) > len(prev.shape) {
+				merged[l.r] = l
+				report.origin[l.r] = i
+			}
+		}
+	}
+	for r := range conflicting {
+		report.conflicts[r] = defined[r]
+	}
+
+	if policy == failOnConflict && len(report.conflicts) > 0 {
+		return nil, report, fmt.Errorf(
+			"%d letters are defined differently:\n%s",
+			len(report.conflicts), report.conflictList(),
+		)
+	}
+
+	var out letters
+	for _, l := range merged {
+		out = append(out, l)
+	}
+	sort.Sort(out)
+	return out, report, nil
+}
+
+// sameStrokes tells if a and b draw the same. The order and direction of the
+// strokes do not matter since saving a font reorders them, see linearize.
+func sameStrokes(a, b strokes) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	used := make([]bool, len(b))
+	for _, s := range a {
+		found := false
+		for j, t := range b {
+			if !used[j] && sameStroke(s, t) {
+				used[j] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// conflictList has a line for every conflict with the sources that define the
+// rune and the one that the letter was taken from, if any.
+func (r mergeReport) conflictList() string {
+	var runes []rune
+	for c := range r.conflicts {
+		runes = append(runes, c)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	var s string
+	for _, c := range runes {
+		var names []string
+		for _, i := range r.conflicts[c] {
+			names = append(names, r.sources[i])
+		}
+		s += fmt.Sprintf("\t%s in %s", letterName(c), strings.Join(names, ", "))
+		if r.policy != failOnConflict {
+			s += ", taken from " + r.sources[r.origin[c]]
+		}
+		s += "\n"
+	}
+	return s
+}
+
+// String lists the letters taken from each source and the conflicts.
+func (r mergeReport) String() string {
+	taken := make([][]rune, len(r.sources))
+	for c, i := range r.origin {
+		taken[i] = append(taken[i], c)
+	}
+	var s string
+	for i, name := range r.sources {
+		runes := taken[i]
+		sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+		var names []string
+		for _, c := range runes {
+			if c == notdef {
+				names = append(names, letterName(c))
+			} else {
+				names = append(names, runeList([]rune{c}))
+			}
+		}
+		s += fmt.Sprintf("%s (%d)\n\t%s\n", name, len(runes), strings.Join(names, " "))
+	}
+	if len(r.conflicts) > 0 {
+		s += fmt.Sprintf("conflicts (%d):\n%s", len(r.conflicts), r.conflictList())
+	}
+	return s
+}
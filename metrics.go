@@ -0,0 +1,60 @@
+package main
+
+import "unicode"
+
+// spacingRule tells autoMetrics how to space the letters of a font. All
+// distances are in canvas units.
+type spacingRule struct {
+	// left is the gap between the left of the canvas and the left-most ink of
+	// a letter, right the gap between its right-most ink and the advance.
+	left, right float64
+	// penWidth is the width of the pen that the font is drawn with. Half of
+	// it is added around the strokes to get the inked area.
+	penWidth float64
+	// monospace is the advance of every letter if it is not 0. The ink of
+	// each letter is then centered between 0 and the advance and left and
+	// right are ignored.
+	monospace float64
+	// space is the advance of white space letters without strokes, which do
+	// not have one yet.
+	space float64
+}
+
+var defaultSpacing = spacingRule{
+	left:  0.05,
+	right: 0.05,
+	space: emptyAdvance,
+}
+
+// autoMetrics returns a copy of the list where each letter's strokes are moved
+// horizontally to get the rule's left side bearing and the advance is set so
+// the right side bearing is as required. Letters without strokes keep their
+// advance unless the font is monospaced. White space gets the rule's space
+// advance if it has none, other empty letters are not part of the font, see
+// hasGlyph, and are left alone.
+func autoMetrics(list letters, rule spacingRule) letters {
+	out := make(letters, 0, len(list))
+	for _, l := range list {
+		ink := l.shape.bounds().inflate(rule.penWidth / 2)
+		if ink.empty() {
+			if l.advance == 0 && unicode.IsSpace(l.r) {
+				l.advance = rule.space
+			}
+			if rule.monospace != 0 && (hasGlyph(l) || unicode.IsSpace(l.r)) {
+				l.advance = rule.monospace
+			}
+			out = append(out, l)
+			continue
+		}
+
+		dx := rule.left - ink.minX
+		l.advance = rule.left + ink.width() + rule.right
+		if rule.monospace != 0 {
+			dx = (rule.monospace-ink.width())/2 - ink.minX
+			l.advance = rule.monospace
+		}
+		l.shape = l.shape.transformed(translation(dx, 0))
+		out = append(out, l)
+	}
+	return out
+}
@@ -0,0 +1,524 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+type outlineOptions struct {
+	// penWidth is the diameter of a circular pen or the side length of a
+	// rectangular pen, in canvas units.
+	penWidth float64
+	pen      penShape
+	// tolerance is the maximum distance between the computed outline and the
+	// exact one, in canvas units. The outline's curved parts, i.e. offset
+	// curves and round caps and joins, are made of straight edges that are at
+	// most this far off.
+	tolerance float64
+}
+
+// outline returns the area that the pen covers when drawing the shape, as
+// closed polygons that do not overlap. The polygons' last points are not
+// repeated. Outer borders have a positive polygonArea, holes, like the inside
+// of an 'O', have a negative area.
+//
+// A circular pen draws the area between a curve's two offset curves at half
+// the pen width, with round caps. These offset curves are computed by
+// offsetCurve, the area between them is split into quadrilaterals and the
+// caps are the pen polygon, see penPolygon, at both ends. Lines, and all
+// strokes drawn with the rectangular pen, are flattened and the pen is moved
+// along each piece. The area that it sweeps is the convex hull of the pen at
+// the piece's start and end, which is exact for the square pen because it
+// does not rotate. The union of all pieces has round or square joins where
+// strokes meet.
+func outline(shape strokes, opt outlineOptions) [][][2]float64 {
+	if opt.penWidth <= 0 || opt.tolerance <= 0 {
+		return nil
+	}
+
+	pen := penPolygon(opt)
+	var pieces [][][2]float64
+	for _, s := range shape {
+		if s.typ == curve && opt.pen == circular {
+			left, right := offsetCurve(s, opt.penWidth/2, opt.tolerance)
+			for i := 1; i < len(left); i++ {
+				quad := convexHull([][2]float64{
+					snapPoint(left[i-1]), snapPoint(right[i-1]),
+					snapPoint(left[i]), snapPoint(right[i]),
+				})
+				if len(quad) >= 3 {
+					pieces = append(pieces, quad)
+				}
+			}
+			pieces = append(pieces,
+				movePolygon(pen, [2]float64{s.x1, s.y1}),
+				movePolygon(pen, [2]float64{s.x3, s.y3}),
+			)
+			if cusp, ok := curveCusp(s); ok {
+				pieces = append(pieces, movePolygon(pen, cusp))
+			}
+			continue
+		}
+		points := s.flatten(opt.tolerance)
+		n := len(pieces)
+		for i := 1; i < len(points); i++ {
+			a, b := points[i-1], points[i]
+			if a == b {
+				continue
+			}
+			pieces = append(pieces, convexHull(append(
+				movePolygon(pen, a),
+				movePolygon(pen, b)...,
+			)))
+		}
+		if len(pieces) == n {
+			// dots and lines of length 0
+			pieces = append(pieces, movePolygon(pen, points[0]))
+		}
+	}
+	return unionConvex(pieces)
+}
+
+// layoutOutlines is like outline for letters that were laid out in output
+// units, see layoutText. The options' pen width and tolerance are in output
+// units as well. Letters that overlap are merged into the same polygons.
+func layoutOutlines(placed []placedLetter, opt outlineOptions) [][][2]float64 {
+	var shape strokes
+	for _, l := range placed {
+		toOutput := scaling(l.size, l.size).then(translation(l.x, l.y))
+		shape = append(shape, l.shape.transformed(toOutput)...)
+	}
+	return outline(shape, opt)
+}
+
+// offsetCurve returns the quadratic curve's offset curves at distance d to
+// both sides, as polylines with the same number of points. Points with the
+// same index are at the same point of the curve, along its normal there. The
+// curve is split in halves until the curve and both offset curves are within
+// the tolerance of the lines between the points.
+//
+// Where the curve bends tighter than d, the inner offset curve has a cusp and
+// runs backwards for a while. The quadrilaterals between neighboring points
+// still cover the area that a circular pen draws, because every point within
+// d of the curve is on one of its normals or within d of its ends or of the
+// point returned by curveCusp.
+func offsetCurve(s stroke, d, tolerance float64) (left, right [][2]float64) {
+	a := [2]float64{s.x1, s.y1}
+	b := [2]float64{s.x2, s.y2}
+	c := [2]float64{s.x3, s.y3}
+	at := func(t float64) (center, l, r [2]float64) {
+		u := 1 - t
+		var tangent [2]float64
+		for k := 0; k < 2; k++ {
+			center[k] = u*u*a[k] + 2*u*t*b[k] + t*t*c[k]
+			tangent[k] = 2*u*(b[k]-a[k]) + 2*t*(c[k]-b[k])
+		}
+		length := math.Hypot(tangent[0], tangent[1])
+		if length < 1e-12 {
+			// the control point is on an end point or the curve turns back
+			// here, either way it runs along the line between its ends
+			tangent = [2]float64{c[0] - a[0], c[1] - a[1]}
+			length = math.Hypot(tangent[0], tangent[1])
+		}
+		if length < 1e-12 {
+			return center, center, center
+		}
+		nx, ny := -tangent[1]/length*d, tangent[0]/length*d
+		return center, [2]float64{center[0] + nx, center[1] + ny},
+			[2]float64{center[0] - nx, center[1] - ny}
+	}
+	// far tells if q is farther than the tolerance from the line from p to r
+	// at parameter f along it
+	far := func(p, q, r [2]float64, f float64) bool {
+		x := p[0] + f*(r[0]-p[0]) - q[0]
+		y := p[1] + f*(r[1]-p[1]) - q[1]
+		return math.Hypot(x, y) > tolerance
+	}
+
+	_, l0, r0 := at(0)
+	left, right = [][2]float64{l0}, [][2]float64{r0}
+	var split func(t0, t1 float64, depth int)
+	split = func(t0, t1 float64, depth int) {
+		c0, l0, r0 := at(t0)
+		c1, l1, r1 := at(t1)
+		// near a cusp the normal turns around while the curve hardly moves,
+		// the pen drawn at the cusp covers that
+		cm, _, _ := at((t0 + t1) / 2)
+		moves := math.Hypot(c1[0]-c0[0], c1[1]-c0[1]) > tolerance/4 ||
+			math.Hypot(cm[0]-c0[0], cm[1]-c0[1]) > tolerance/4
+		if depth < maxFlattenDepth && moves {
+			for _, f := range []float64{0.25, 0.5, 0.75} {
+				cm, lm, rm := at(t0 + f*(t1-t0))
+				if far(c0, cm, c1, f) || far(l0, lm, l1, f) || far(r0, rm, r1, f) {
+					split(t0, (t0+t1)/2, depth+1)
+					split((t0+t1)/2, t1, depth+1)
+					return
+				}
+			}
+		}
+		left = append(left, l1)
+		right = append(right, r1)
+	}
+	split(0, 1, 0)
+	return left, right
+}
+
+// curveCusp returns the point where a quadratic curve is slowest, if that is
+// not one of its ends. There the curve can turn back on itself, like a line
+// that goes forth and back, and has no normal that the offset curves could
+// follow, so the pen has to be drawn there on its own.
+func curveCusp(s stroke) ([2]float64, bool) {
+	// the curve's derivative is 2*(b-a) + 2*t*(a-2b+c)
+	dx, dy := s.x2-s.x1, s.y2-s.y1
+	ax, ay := s.x1-2*s.x2+s.x3, s.y1-2*s.y2+s.y3
+	square := ax*ax + ay*ay
+	if square == 0 {
+		return [2]float64{}, false
+	}
+	t := -(dx*ax + dy*ay) / square
+	if t <= 0 || t >= 1 {
+		return [2]float64{}, false
+	}
+	u := 1 - t
+	return [2]float64{
+		u*u*s.x1 + 2*u*t*s.x2 + t*t*s.x3,
+		u*u*s.y1 + 2*u*t*s.y2 + t*t*s.y3,
+	}, true
+}
+
+// penPolygon returns the pen's tip as a convex polygon around 0,0. A circle is
+// approximated by a regular polygon with its corners on the circle. The
+// corners are always at the same angles so that pieces around the same point
+// share the same corners.
+func penPolygon(opt outlineOptions) [][2]float64 {
+	r := opt.penWidth / 2
+	if opt.pen == rectangular {
+		return [][2]float64{{-r, -r}, {r, -r}, {r, r}, {-r, r}}
+	}
+	n := 8
+	if opt.tolerance < r {
+		// the polygon's edges are at most tolerance inside the circle
+		n = int(math.Ceil(math.Pi / math.Acos(1-opt.tolerance/r)))
+	}
+	if n < 8 {
+		n = 8
+	}
+	pen := make([][2]float64, n)
+	for i := range pen {
+		sin, cos := math.Sincos(2 * math.Pi * float64(i) / float64(n))
+		pen[i] = [2]float64{r * cos, r * sin}
+	}
+	return pen
+}
+
+func movePolygon(polygon [][2]float64, d [2]float64) [][2]float64 {
+	moved := make([][2]float64, len(polygon))
+	for i, p := range polygon {
+		moved[i] = snapPoint([2]float64{p[0] + d[0], p[1] + d[1]})
+	}
+	return moved
+}
+
+// polygonArea returns the polygon's area, which is positive if it is
+// counter-clockwise in a coordinate system with y pointing up.
+func polygonArea(polygon [][2]float64) float64 {
+	area := 0.0
+	for i := range polygon {
+		a, b := polygon[i], polygon[(i+1)%len(polygon)]
+		area += a[0]*b[1] - b[0]*a[1]
+	}
+	return area / 2
+}
+
+// snapPoint rounds to a fine grid so that points which are computed in
+// different ways but are supposed to be the same, actually are the same.
+func snapPoint(p [2]float64) [2]float64 {
+	const grid = 1 << 30
+	return [2]float64{
+		math.Round(p[0]*grid) / grid,
+		math.Round(p[1]*grid) / grid,
+	}
+}
+
+// unionConvex merges the convex, counter-clockwise polygons into polygons that
+// do not overlap.
+//
+// All edges are split where they cross or touch an edge of another polygon.
+// The resulting pieces are kept if they are not inside any other polygon,
+// which makes them part of the union's border. Pieces that lie on the border
+// of another polygon are kept only once if both polygons are on the same side
+// of them and dropped if the polygons touch from opposite sides. The remaining
+// pieces are then connected into closed polygons.
+func unionConvex(polygons [][][2]float64) [][][2]float64 {
+	// eps is the distance below which points are considered the same.
+	const eps = 1e-7
+
+	type box struct{ minX, minY, maxX, maxY float64 }
+	boxes := make([]box, len(polygons))
+	for i, p := range polygons {
+		b := box{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+		for _, v := range p {
+			b.minX, b.minY = math.Min(b.minX, v[0]), math.Min(b.minY, v[1])
+			b.maxX, b.maxY = math.Max(b.maxX, v[0]), math.Max(b.maxY, v[1])
+		}
+		boxes[i] = b
+	}
+	overlap := func(a, b box) bool {
+		return a.minX <= b.maxX+eps && b.minX <= a.maxX+eps &&
+			a.minY <= b.maxY+eps && b.minY <= a.maxY+eps
+	}
+
+	sub := func(a, b [2]float64) [2]float64 { return [2]float64{a[0] - b[0], a[1] - b[1]} }
+	cross := func(a, b [2]float64) float64 { return a[0]*b[1] - a[1]*b[0] }
+	dotProduct := func(a, b [2]float64) float64 { return a[0]*b[0] + a[1]*b[1] }
+	length := func(a [2]float64) float64 { return math.Hypot(a[0], a[1]) }
+
+	// Points that are closer than eps are merged into the one that came first.
+	// Otherwise the intersections of an edge with two other edges that meet
+	// at or very close to it would split the edges at slightly different
+	// points. The grid cells are as large as eps so only the neighboring
+	// cells need to be checked for close points.
+	cells := make(map[[2]int64][][2]float64)
+	merge := func(p [2]float64) [2]float64 {
+		cx, cy := int64(math.Floor(p[0]/eps)), int64(math.Floor(p[1]/eps))
+		for x := cx - 1; x <= cx+1; x++ {
+			for y := cy - 1; y <= cy+1; y++ {
+				for _, q := range cells[[2]int64{x, y}] {
+					if length(sub(p, q)) <= eps {
+						return q
+					}
+				}
+			}
+		}
+		cells[[2]int64{cx, cy}] = append(cells[[2]int64{cx, cy}], p)
+		return p
+	}
+
+	type edge struct {
+		polygon int
+		a, b    [2]float64
+		splits  [][2]float64
+	}
+	var edges []*edge
+	firstEdge := make([]int, len(polygons)+1)
+	for i, p := range polygons {
+		firstEdge[i] = len(edges)
+		for k := range p {
+			a, b := merge(p[k]), merge(p[(k+1)%len(p)])
+			if a != b {
+				edges = append(edges, &edge{polygon: i, a: a, b: b})
+			}
+		}
+	}
+	firstEdge[len(polygons)] = len(edges)
+
+	// strictlyWithin tells if p lies on e but not at its ends
+	strictlyWithin := func(e *edge, p [2]float64) bool {
+		d := sub(e.b, e.a)
+		l := length(d)
+		if math.Abs(cross(d, sub(p, e.a)))/l > eps {
+			return false
+		}
+		t := dotProduct(sub(p, e.a), d) / (l * l)
+		return t*l > eps && (1-t)*l > eps
+	}
+
+	// split all edges where they meet edges of other polygons
+	for i := range polygons {
+		for j := i + 1; j < len(polygons); j++ {
+			if !overlap(boxes[i], boxes[j]) {
+				continue
+			}
+			for _, e := range edges[firstEdge[i]:firstEdge[i+1]] {
+				for _, f := range edges[firstEdge[j]:firstEdge[j+1]] {
+					d1, d2 := sub(e.b, e.a), sub(f.b, f.a)
+					denom := cross(d1, d2)
+					if math.Abs(denom) <= eps*length(d1)*length(d2) {
+						// parallel, maybe overlapping
+						for _, p := range [][2]float64{f.a, f.b} {
+							if strictlyWithin(e, p) {
+								e.splits = append(e.splits, p)
+							}
+						}
+						for _, p := range [][2]float64{e.a, e.b} {
+							if strictlyWithin(f, p) {
+								f.splits = append(f.splits, p)
+							}
+						}
+						continue
+					}
+					w := sub(f.a, e.a)
+					t := cross(w, d2) / denom
+					u := cross(w, d1) / denom
+					te, ue := eps/length(d1), eps/length(d2)
+					if t < -te || t > 1+te || u < -ue || u > 1+ue {
+						continue
+					}
+					p := merge([2]float64{e.a[0] + t*d1[0], e.a[1] + t*d1[1]})
+					if strictlyWithin(e, p) {
+						e.splits = append(e.splits, p)
+					}
+					if strictlyWithin(f, p) {
+						f.splits = append(f.splits, p)
+					}
+				}
+			}
+		}
+	}
+
+	// classify where point m is relative to convex polygon i: inside, outside
+	// or on the border edge with direction dir
+	const (
+		outside = iota
+		inside
+		onBorder
+	)
+	classify := func(m [2]float64, i int) (where int, dir [2]float64) {
+		p := polygons[i]
+		where = inside
+		for k := range p {
+			a, b := p[k], p[(k+1)%len(p)]
+			d := sub(b, a)
+			l := length(d)
+			if l == 0 {
+				continue
+			}
+			c := cross(d, sub(m, a)) / l
+			if c < -eps {
+				return outside, dir
+			}
+			if c <= eps {
+				where, dir = onBorder, d
+			}
+		}
+		return where, dir
+	}
+
+	type piece struct {
+		a, b [2]float64
+		used bool
+	}
+	var pieces []*piece
+	for _, e := range edges {
+		d := sub(e.b, e.a)
+		points := append([][2]float64{e.a, e.b}, e.splits...)
+		sort.Slice(points, func(i, j int) bool {
+			return dotProduct(sub(points[i], e.a), d) < dotProduct(sub(points[j], e.a), d)
+		})
+		// Leave out split points too close to the previous one, the pieces
+		// in between would be too short to classify them correctly. The edge's
+		// end points must be kept though.
+		merged := points[:1]
+		for _, p := range points[1:] {
+			if length(sub(p, merged[len(merged)-1])) > eps {
+				merged = append(merged, p)
+			} else if p == e.b {
+				merged[len(merged)-1] = p
+			}
+		}
+		points = merged
+		for k := 1; k < len(points); k++ {
+			a, b := points[k-1], points[k]
+			if a == b {
+				continue
+			}
+			m := [2]float64{(a[0] + b[0]) / 2, (a[1] + b[1]) / 2}
+			keep := true
+			for j := range polygons {
+				if j == e.polygon ||
+					m[0] < boxes[j].minX-eps || m[0] > boxes[j].maxX+eps ||
+					m[1] < boxes[j].minY-eps || m[1] > boxes[j].maxY+eps {
+					continue
+				}
+				where, dir := classify(m, j)
+				sameSide := dotProduct(dir, d) > 0
+				if where == inside ||
+					where == onBorder && !sameSide ||
+					where == onBorder && sameSide && j < e.polygon {
+					keep = false
+					break
+				}
+			}
+			if keep {
+				pieces = append(pieces, &piece{a: a, b: b})
+			}
+		}
+	}
+
+	// connect the pieces to closed polygons
+	starting := make(map[[2]float64][]*piece)
+	for _, p := range pieces {
+		starting[p.a] = append(starting[p.a], p)
+	}
+	var union [][][2]float64
+	for _, first := range pieces {
+		if first.used {
+			continue
+		}
+		var polygon [][2]float64
+		for p := first; p != nil; {
+			p.used = true
+			polygon = append(polygon, p.a)
+			// Where more than one piece continues, polygons touch in a
+			// single point. The inside is on the left of every piece, so
+			// taking the sharpest left turn keeps them apart.
+			in := sub(p.b, p.a)
+			var next *piece
+			bestAngle := math.Inf(-1)
+			for _, q := range starting[p.b] {
+				if q.used {
+					continue
+				}
+				out := sub(q.b, q.a)
+				angle := math.Atan2(cross(in, out), dotProduct(in, out))
+				if angle > bestAngle {
+					bestAngle = angle
+					next = q
+				}
+			}
+			if next == nil && p.b != first.a {
+				// Where edges cross very close to a corner, a few of the
+				// tiny pieces between the crossings and the corner can be
+				// misclassified, leaving a gap that is not much larger than
+				// eps. The polygon continues with the closest piece.
+				closest := 10 * eps
+				for _, q := range pieces {
+					if d := length(sub(q.a, p.b)); !q.used && d < closest {
+						closest = d
+						next = q
+					}
+				}
+			}
+			p = next
+		}
+		polygon = removeCollinear(polygon)
+		if len(polygon) >= 3 {
+			union = append(union, polygon)
+		}
+	}
+	return union
+}
+
+// removeCollinear removes points from the closed polygon that lie on a straight
+// line between their neighbors.
+func removeCollinear(polygon [][2]float64) [][2]float64 {
+	for changed := true; changed && len(polygon) >= 3; {
+		changed = false
+		for i := 0; i < len(polygon) && len(polygon) >= 3; i++ {
+			a := polygon[(i+len(polygon)-1)%len(polygon)]
+			b := polygon[i]
+			c := polygon[(i+1)%len(polygon)]
+			ab := [2]float64{b[0] - a[0], b[1] - a[1]}
+			bc := [2]float64{c[0] - b[0], c[1] - b[1]}
+			cross := ab[0]*bc[1] - ab[1]*bc[0]
+			dot := ab[0]*bc[0] + ab[1]*bc[1]
+			if math.Abs(cross) <= 1e-12*math.Hypot(ab[0], ab[1])*math.Hypot(bc[0], bc[1]) &&
+				dot >= 0 {
+				polygon = append(polygon[:i], polygon[i+1:]...)
+				changed = true
+				i--
+			}
+		}
+	}
+	return polygon
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestOutline(t *testing.T) {
+	const r = 0.05
+	// arc is a quarter of a circle with radius 0.3, approximately
+	arc := stroke{typ: curve, x1: 0.2, y1: 0.8, x2: 0.2, y2: 0.2, x3: 0.8, y3: 0.2}
+	arcLength := 0.0
+	points := arc.flatten(1e-6)
+	for i := 1; i < len(points); i++ {
+		arcLength += math.Hypot(points[i][0]-points[i-1][0], points[i][1]-points[i-1][1])
+	}
+
+	tests := []struct {
+		name     string
+		shape    strokes
+		pen      penShape
+		polygons int
+		area     float64
+	}{
+		{
+			"square dot",
+			strokes{{typ: dot, x1: 0.5, y1: 0.5}},
+			rectangular, 1, 4 * r * r,
+		},
+		{
+			"round line",
+			strokes{{typ: line, x1: 0.2, y1: 0.5, x2: 0.8, y2: 0.5}},
+			circular, 1, 0.6*2*r + math.Pi*r*r,
+		},
+		{
+			"round curve",
+			strokes{arc},
+			circular, 1, arcLength*2*r + math.Pi*r*r,
+		},
+		{
+			"overlapping lines",
+			strokes{
+				{typ: line, x1: 0.2, y1: 0.5, x2: 0.8, y2: 0.5},
+				{typ: line, x1: 0.5, y1: 0.2, x2: 0.5, y2: 0.8},
+			},
+			rectangular, 1, 2*0.7*2*r - 4*r*r,
+		},
+		{
+			"same line twice",
+			strokes{
+				{typ: line, x1: 0.2, y1: 0.5, x2: 0.8, y2: 0.5},
+				{typ: line, x1: 0.8, y1: 0.5, x2: 0.2, y2: 0.5},
+			},
+			rectangular, 1, 0.7 * 2 * r,
+		},
+		{
+			"dots touching along an edge",
+			strokes{{typ: dot, x1: 0.3, y1: 0.5}, {typ: dot, x1: 0.4, y1: 0.5}},
+			rectangular, 1, 8 * r * r,
+		},
+		{
+			"dots touching in a corner",
+			strokes{{typ: dot, x1: 0.3, y1: 0.3}, {typ: dot, x1: 0.4, y1: 0.4}},
+			rectangular, 2, 8 * r * r,
+		},
+		{
+			"separate strokes",
+			strokes{
+				{typ: dot, x1: 0.2, y1: 0.2},
+				{typ: line, x1: 0.5, y1: 0.5, x2: 0.8, y2: 0.5},
+			},
+			circular, 2, math.Pi*r*r + 0.3*2*r + math.Pi*r*r,
+		},
+		{
+			"frame with a hole",
+			strokes{
+				{typ: line, x1: 0.2, y1: 0.2, x2: 0.8, y2: 0.2},
+				{typ: line, x1: 0.8, y1: 0.2, x2: 0.8, y2: 0.8},
+				{typ: line, x1: 0.8, y1: 0.8, x2: 0.2, y2: 0.8},
+				{typ: line, x1: 0.2, y1: 0.8, x2: 0.2, y2: 0.2},
+			},
+			rectangular, 2, 0.7*0.7 - 0.5*0.5,
+		},
+	}
+	for _, test := range tests {
+		opt := outlineOptions{penWidth: 2 * r, pen: test.pen, tolerance: 0.001}
+		polygons := outline(test.shape, opt)
+		if len(polygons) != test.polygons {
+			t.Errorf("%s: %d polygons, want %d", test.name, len(polygons), test.polygons)
+			continue
+		}
+		// holes are oriented the other way around and subtract their area
+		area := 0.0
+		for _, p := range polygons {
+			area += polygonArea(p)
+		}
+		// every point of the border is at most the tolerance off, the area
+		// is then off by at most the tolerance times the border's length
+		border := 0.0
+		for _, p := range polygons {
+			for i := range p {
+				a, b := p[i], p[(i+1)%len(p)]
+				border += math.Hypot(b[0]-a[0], b[1]-a[1])
+			}
+		}
+		if math.Abs(math.Abs(area)-test.area) > opt.tolerance*border {
+			t.Errorf("%s: area is %g, want %g", test.name, math.Abs(area), test.area)
+		}
+	}
+}
+
+func TestOutlineOfCurveFollowsOffsetCurves(t *testing.T) {
+	const r = 0.05
+	s := stroke{typ: curve, x1: 0.1, y1: 0.5, x2: 0.8, y2: 0.4, x3: 0.4, y3: 0.1}
+	opt := outlineOptions{penWidth: 2 * r, pen: circular, tolerance: 0.002}
+	polygons := outline(strokes{s}, opt)
+	if len(polygons) != 1 {
+		t.Fatalf("%d polygons, want 1", len(polygons))
+	}
+	points := s.flatten(1e-6)
+	for _, corner := range polygons[0] {
+		d := math.Inf(1)
+		for i := 1; i < len(points); i++ {
+			d = math.Min(d, segmentDistance(points[i-1], points[i], corner[0], corner[1]))
+		}
+		if d < r-opt.tolerance || d > r+opt.tolerance {
+			t.Errorf("corner %v is %g away from the curve, want %g", corner, d, r)
+		}
+	}
+}
+
+func TestLayoutOutlines(t *testing.T) {
+	dot := strokes{{typ: dot, x1: 0.5, y1: 0.5}}
+	opt := outlineOptions{penWidth: 2, pen: rectangular, tolerance: 0.01}
+	tests := []struct {
+		name     string
+		placed   []placedLetter
+		polygons int
+		area     float64
+	}{
+		{
+			"separate letters",
+			[]placedLetter{{x: 0, size: 10, shape: dot}, {x: 10, size: 10, shape: dot}},
+			2, 8,
+		},
+		{
+			"overlapping letters",
+			[]placedLetter{{x: 0, size: 10, shape: dot}, {x: 1, size: 10, shape: dot}},
+			1, 6,
+		},
+	}
+	for _, test := range tests {
+		polygons := layoutOutlines(test.placed, opt)
+		if len(polygons) != test.polygons {
+			t.Errorf("%s: %d polygons, want %d", test.name, len(polygons), test.polygons)
+			continue
+		}
+		area := 0.0
+		for _, p := range polygons {
+			area += polygonArea(p)
+		}
+		if math.Abs(math.Abs(area)-test.area) > 1e-9 {
+			t.Errorf("%s: area is %g, want %g", test.name, math.Abs(area), test.area)
+		}
+	}
+}
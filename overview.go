@@ -0,0 +1,34 @@
+package main
+
+// overviewPage is a page of the editor's glyph overview with the runes of one
+// Unicode block.
+type overviewPage struct {
+	name  string
+	runes []rune
+}
+
+// overviewPages returns a page for every Unicode block with letters in the
+// font or runes in the target character set, in the order of the blocks. A
+// page has the runes of both, sorted, so the missing ones can be shown too.
+// Runes in otherBlock are on a last page. The notdef glyph is not on any page.
+func overviewPages(list letters, target characterSet) []overviewPage {
+	present := make(map[rune]bool)
+	for _, l := range list {
+		if hasGlyph(l) && l.r != notdef {
+			present[l.r] = true
+		}
+	}
+	for _, r := range target.runes {
+		present[r] = true
+	}
+	runes := make([]rune, 0, len(present))
+	for r := range present {
+		runes = append(runes, r)
+	}
+
+	var pages []overviewPage
+	for _, b := range groupByBlock(runes) {
+		pages = append(pages, overviewPage{name: b.block.name, runes: b.runes})
+	}
+	return pages
+}
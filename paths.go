@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+)
+
+type pathOptions struct {
+	// tolerance is the maximum distance between a curve and the polyline that
+	// replaces it, in output units before the transform.
+	tolerance float64
+	// transform is applied to all points after laying out the text. It is
+	// optional.
+	transform *affine
+}
+
+// textPaths returns the lines that a pen has to draw to write the text, in the
+// order of drawing. The text is set in the box with the layout options, see
+// layoutText, in output units with y pointing down. Every path is a polyline for which the pen goes
+// down at the first point and up at the last one. Strokes that linearize puts
+// one after the other, with one ending where the next starts, are joined into
+// a single path. Dots are paths of only one point.
+func textPaths(f *font, text string, box layoutBox, layout layoutOptions, opt pathOptions) [][][2]float64 {
+	return layoutPaths(layoutText(f, text, box, layout), opt)
+}
+
+// layoutPaths is like textPaths for letters that were laid out in output
+// units, see layoutText.
+func layoutPaths(placed []placedLetter, opt pathOptions) [][][2]float64 {
+	transform := identity
+	if opt.transform != nil {
+		transform = *opt.transform
+	}
+
+	var paths [][][2]float64
+	for _, l := range placed {
+		toOutput := func(p [2]float64) [2]float64 {
+			x, y := transform.apply(l.x+p[0]*l.size, l.y+p[1]*l.size)
+			return [2]float64{x, y}
+		}
+		shape := linearize(l.shape)
+		for i, s := range shape {
+			points := s.flatten(opt.tolerance / l.size)
+			if i == 0 || shape[i-1].end() != s.start() {
+				paths = append(paths, nil)
+			} else {
+				// the path already ends in this stroke's first point
+				points = points[1:]
+			}
+			path := &paths[len(paths)-1]
+			for _, p := range points {
+				*path = append(*path, toOutput(p))
+			}
+		}
+	}
+	return paths
+}
+
+// exportSVGPaths writes the paths, e.g. from textPaths, as an SVG drawing for
+// plotters and vinyl cutters. Every path becomes an unfilled SVG path with
+// the given stroke width and round or square ends. Coordinates are used as
+// they are, in SVG user units.
+func exportSVGPaths(paths [][][2]float64, path string, strokeWidth float64, pen penShape) error {
+	return exportSVGLayers([]svgLayer{{
+		paths: paths,
+		color: "black",
+		width: strokeWidth,
+	}}, path, pen)
+}
+
+// exportSVGOutlines writes the polygons, e.g. from layoutOutlines, as an SVG
+// drawing of filled areas, for laser engravers and cutters that fill shapes.
+// All polygons are in one path with the nonzero fill rule, so holes, which go
+// around the other way, are left empty.
+func exportSVGOutlines(polygons [][][2]float64, path string) error {
+	b := emptyBounds
+	for _, p := range polygons {
+		for _, q := range p {
+			b = b.add(q[0], q[1])
+		}
+	}
+	if b.empty() {
+		b = bounds{}
+	}
+
+	var buf bytes.Buffer
+	w := &buf
+	w.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(
+		w,
+		`<svg xmlns="http://www.w3.org/2000/svg" viewBox="%s %s %s %s" width="%s" height="%s">`+"\n",
+		svgNumber(b.minX), svgNumber(b.minY),
+		svgNumber(b.width()), svgNumber(b.height()),
+		svgNumber(b.width()), svgNumber(b.height()),
+	)
+	w.WriteString(`<path fill="black" fill-rule="nonzero" d="`)
+	for i, p := range polygons {
+		if i > 0 {
+			w.WriteString(" ")
+		}
+		fmt.Fprintf(w, "M %s %s", svgNumber(p[0][0]), svgNumber(p[0][1]))
+		for _, q := range p[1:] {
+			fmt.Fprintf(w, " L %s %s", svgNumber(q[0]), svgNumber(q[1]))
+		}
+		w.WriteString(" Z")
+	}
+	w.WriteString(`"/>` + "\n")
+	w.WriteString("</svg>\n")
+	return ioutil.WriteFile(path, buf.Bytes(), 0666)
+}
+
+// svgLayer is a group of paths that are drawn with the same color and stroke
+// width.
+type svgLayer struct {
+	paths [][][2]float64
+	// color is an SVG color, e.g. "black" or "rgba(255,0,0,0.5)".
+	color string
+	width float64
+}
+
+// exportSVGLayers is like exportSVGPaths for several groups of paths, which
+// are drawn in order.
+func exportSVGLayers(layers []svgLayer, path string, pen penShape) error {
+	b := emptyBounds
+	for _, l := range layers {
+		layer := emptyBounds
+		for _, p := range l.paths {
+			for _, q := range p {
+				layer = layer.add(q[0], q[1])
+			}
+		}
+		b = b.union(layer.inflate(l.width / 2))
+	}
+	if b.empty() {
+		b = bounds{}
+	}
+
+	linecap, linejoin := "round", "round"
+	if pen == rectangular {
+		linecap, linejoin = "square", "miter"
+	}
+
+	var buf bytes.Buffer
+	w := &buf
+	w.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(
+		w,
+		`<svg xmlns="http://www.w3.org/2000/svg" viewBox="%s %s %s %s" width="%s" height="%s">`+"\n",
+		svgNumber(b.minX), svgNumber(b.minY),
+		svgNumber(b.width()), svgNumber(b.height()),
+		svgNumber(b.width()), svgNumber(b.height()),
+	)
+	for _, l := range layers {
+		fmt.Fprintf(
+			w,
+			`<g fill="none" stroke="%s" stroke-width="%s" stroke-linecap="%s" stroke-linejoin="%s">`+"\n",
+			l.color, svgNumber(l.width), linecap, linejoin,
+		)
+		for _, p := range l.paths {
+			if len(p) == 0 {
+				continue
+			}
+			fmt.Fprintf(w, `<path d="M %s %s`, svgNumber(p[0][0]), svgNumber(p[0][1]))
+			if len(p) == 1 {
+				// a dot is a line of length 0 which gets drawn because of the
+				// caps
+				p = append(p, p[0])
+			}
+			for _, q := range p[1:] {
+				fmt.Fprintf(w, " L %s %s", svgNumber(q[0]), svgNumber(q[1]))
+			}
+			w.WriteString(`"/>` + "\n")
+		}
+		w.WriteString("</g>\n")
+	}
+	w.WriteString("</svg>\n")
+	return ioutil.WriteFile(path, buf.Bytes(), 0666)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"image"
+	"math"
+)
+
+// referenceImage is a scan or photo that is shown under a letter in the
+// editor, for tracing it. It is stored in the editor's settings.
+type referenceImage struct {
+	Path string
+	// Opacity goes from 0 for an invisible to 1 for the original image.
+	Opacity float64
+	// Scale is the image's height in canvas heights. X and Y are its top-left
+	// corner in canvas coordinates.
+	Scale float64
+	X, Y  float64
+}
+
+func newReferenceImage(path string) referenceImage {
+	return referenceImage{Path: path, Opacity: 0.5, Scale: 1}
+}
+
+// visiblePart returns the part of the image, in image pixels, that is visible
+// on the canvas and where on the screen it goes. The canvas is the square on
+// the screen that the letter is drawn in. It returns false if nothing is
+// visible.
+func (r referenceImage) visiblePart(imageW, imageH int, canvas image.Rectangle) (src, dest image.Rectangle, ok bool) {
+	if imageW <= 0 || imageH <= 0 || r.Scale <= 0 {
+		return src, dest, false
+	}
+	size := float64(canvas.Dx())
+	// k is the size of an image pixel on the screen
+	k := r.Scale * size / float64(imageH)
+	left := float64(canvas.Min.X) + r.X*size
+	top := float64(canvas.Min.Y) + r.Y*size
+	full := image.Rect(
+		int(math.Floor(left)), int(math.Floor(top)),
+		int(math.Ceil(left+float64(imageW)*k)), int(math.Ceil(top+float64(imageH)*k)),
+	)
+	dest = full.Intersect(canvas)
+	if dest.Empty() {
+		return src, dest, false
+	}
+	toImage := func(screen int, start float64) int {
+		return int(math.Floor((float64(screen)-start)/k + 0.5))
+	}
+	src = image.Rect(
+		toImage(dest.Min.X, left), toImage(dest.Min.Y, top),
+		toImage(dest.Max.X, left), toImage(dest.Max.Y, top),
+	).Intersect(image.Rect(0, 0, imageW, imageH))
+	if src.Empty() {
+		return src, dest, false
+	}
+	return src, dest, true
+}
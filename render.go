@@ -0,0 +1,190 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+)
+
+type renderOptions struct {
+	// penWidth is the diameter of a circular pen or the side length of a
+	// rectangular pen, in pixels.
+	penWidth float64
+	// pen is the tip's shape. A circular pen gives round caps and joins, a
+	// rectangular pen is an axis-aligned square like in the editor and gives
+	// square caps and joins.
+	pen   penShape
+	color color.Color
+}
+
+// renderText draws the text in the given font onto img, anti-aliased. It is
+// set in the box with the layout options, see layoutText, in pixels.
+func renderText(img *image.RGBA, f *font, text string, box layoutBox, layout layoutOptions, opt renderOptions) {
+	renderLayout(img, layoutText(f, text, box, layout), opt)
+}
+
+// renderLayout draws letters that were laid out in pixel coordinates, see
+// layoutText.
+func renderLayout(img *image.RGBA, text []placedLetter, opt renderOptions) {
+	var lines [][2][2]float64
+	for _, l := range text {
+		tolerance := 0.2 / l.size
+		toPixel := func(p [2]float64) [2]float64 {
+			return [2]float64{l.x + p[0]*l.size, l.y + p[1]*l.size}
+		}
+		for _, s := range l.shape {
+			points := s.flatten(tolerance)
+			if len(points) == 1 {
+				p := toPixel(points[0])
+				lines = append(lines, [2][2]float64{p, p})
+			}
+			for i := 1; i < len(points); i++ {
+				lines = append(lines, [2][2]float64{
+					toPixel(points[i-1]),
+					toPixel(points[i]),
+				})
+			}
+		}
+	}
+	renderLines(img, lines, opt)
+}
+
+// renderLines draws the given lines, all with the same pen. Each pixel is
+// covered as much as the line that covers it most. This is not exact but
+// unlike drawing the lines one after another, it does not make joins darker
+// for transparent colors.
+func renderLines(img *image.RGBA, lines [][2][2]float64, opt renderOptions) {
+	if len(lines) == 0 || opt.penWidth <= 0 {
+		return
+	}
+	r := opt.penWidth / 2
+
+	// find the area that needs to be drawn
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, l := range lines {
+		for _, p := range l {
+			minX, minY = math.Min(minX, p[0]), math.Min(minY, p[1])
+			maxX, maxY = math.Max(maxX, p[0]), math.Max(maxY, p[1])
+		}
+	}
+	bounds := image.Rect(
+		int(math.Floor(minX-r-1)), int(math.Floor(minY-r-1)),
+		int(math.Ceil(maxX+r+1)), int(math.Ceil(maxY+r+1)),
+	).Intersect(img.Bounds())
+	if bounds.Empty() {
+		return
+	}
+
+	mask := image.NewAlpha(bounds)
+	for _, l := range lines {
+		a, b := l[0], l[1]
+		var coverage func(x, y float64) float64
+		if opt.pen == rectangular {
+			hull := convexHull([][2]float64{
+				{a[0] - r, a[1] - r}, {a[0] + r, a[1] - r},
+				{a[0] + r, a[1] + r}, {a[0] - r, a[1] + r},
+				{b[0] - r, b[1] - r}, {b[0] + r, b[1] - r},
+				{b[0] + r, b[1] + r}, {b[0] - r, b[1] + r},
+			})
+			coverage = func(x, y float64) float64 {
+				return 0.5 - polygonDistance(hull, x, y)
+			}
+		} else {
+			coverage = func(x, y float64) float64 {
+				return r + 0.5 - segmentDistance(a, b, x, y)
+			}
+		}
+
+		area := image.Rect(
+			int(math.Floor(math.Min(a[0], b[0])-r-1)),
+			int(math.Floor(math.Min(a[1], b[1])-r-1)),
+			int(math.Ceil(math.Max(a[0], b[0])+r+1)),
+			int(math.Ceil(math.Max(a[1], b[1])+r+1)),
+		).Intersect(bounds)
+		for y := area.Min.Y; y < area.Max.Y; y++ {
+			for x := area.Min.X; x < area.Max.X; x++ {
+				c := coverage(float64(x)+0.5, float64(y)+0.5)
+				if c <= 0 {
+					continue
+				}
+				alpha := uint8(255)
+				if c < 1 {
+					alpha = uint8(c*255 + 0.5)
+				}
+				i := mask.PixOffset(x, y)
+				if alpha > mask.Pix[i] {
+					mask.Pix[i] = alpha
+				}
+			}
+		}
+	}
+
+	draw.DrawMask(
+		img, bounds,
+		image.NewUniform(opt.color), image.Point{},
+		mask, bounds.Min,
+		draw.Over,
+	)
+}
+
+// segmentDistance returns the distance of point x,y to the line from a to b.
+func segmentDistance(a, b [2]float64, x, y float64) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	t := 0.0
+	if lenSquare := dx*dx + dy*dy; lenSquare > 0 {
+		t = ((x-a[0])*dx + (y-a[1])*dy) / lenSquare
+		t = math.Max(0, math.Min(1, t))
+	}
+	return math.Hypot(x-(a[0]+t*dx), y-(a[1]+t*dy))
+}
+
+// convexHull returns the convex hull of the points in counter-clockwise order,
+// in a coordinate system where y points up.
+func convexHull(points [][2]float64) [][2]float64 {
+	p := append([][2]float64(nil), points...)
+	sort.Slice(p, func(i, j int) bool {
+		return p[i][0] < p[j][0] || p[i][0] == p[j][0] && p[i][1] < p[j][1]
+	})
+	cross := func(o, a, b [2]float64) float64 {
+		return (a[0]-o[0])*(b[1]-o[1]) - (a[1]-o[1])*(b[0]-o[0])
+	}
+	// Andrew's monotone chain, lower and then upper part of the hull
+	var hull [][2]float64
+	for _, pass := range []int{0, 1} {
+		start := len(hull)
+		for i := range p {
+			q := p[i]
+			if pass == 1 {
+				q = p[len(p)-1-i]
+			}
+			for len(hull) >= start+2 &&
+				cross(hull[len(hull)-2], hull[len(hull)-1], q) <= 0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, q)
+		}
+		hull = hull[:len(hull)-1]
+	}
+	return hull
+}
+
+// polygonDistance returns the distance of point x,y to the border of the
+// convex polygon. It is negative inside the polygon.
+func polygonDistance(polygon [][2]float64, x, y float64) float64 {
+	d := math.Inf(1)
+	inside := true
+	for i := range polygon {
+		a, b := polygon[i], polygon[(i+1)%len(polygon)]
+		d = math.Min(d, segmentDistance(a, b, x, y))
+		if (b[0]-a[0])*(y-a[1])-(b[1]-a[1])*(x-a[0]) < 0 {
+			inside = false
+		}
+	}
+	if inside {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// pointRef is a control point of the edited letter, the index of its stroke
+// and which of the stroke's points it is, 0 for x1,y1, 1 for x2,y2 and 2 for
+// x3,y3.
+type pointRef struct {
+	stroke, point int
+}
+
+// selection is a set of control points that are moved, scaled and rotated
+// together in the editor. Its references are only valid as long as no strokes
+// are removed from or reordered in the letter.
+type selection map[pointRef]bool
+
+// pointCount is how many control points the stroke has.
+func (s *stroke) pointCount() int {
+	switch s.typ {
+	case dot:
+		return 1
+	case line:
+		return 2
+	case curve:
+		return 3
+	default:
+		panic("unknown stroke type")
+	}
+}
+
+// point returns the coordinates of the stroke's i'th control point.
+func (s *stroke) point(i int) (x, y *float64) {
+	switch i {
+	case 0:
+		return &s.x1, &s.y1
+	case 1:
+		return &s.x2, &s.y2
+	default:
+		return &s.x3, &s.y3
+	}
+}
+
+// selectStroke adds all points of the stroke to the selection.
+func (sel selection) selectStroke(shape strokes, i int) {
+	for p := 0; p < shape[i].pointCount(); p++ {
+		sel[pointRef{stroke: i, point: p}] = true
+	}
+}
+
+// hasStroke tells if all points of the stroke are selected.
+func (sel selection) hasStroke(shape strokes, i int) bool {
+	for p := 0; p < shape[i].pointCount(); p++ {
+		if !sel[pointRef{stroke: i, point: p}] {
+			return false
+		}
+	}
+	return true
+}
+
+// strokes returns the indices of all strokes with selected points, in order.
+func (sel selection) strokes() []int {
+	seen := make(map[int]bool)
+	var list []int
+	for ref := range sel {
+		if !seen[ref.stroke] {
+			seen[ref.stroke] = true
+			list = append(list, ref.stroke)
+		}
+	}
+	sort.Ints(list)
+	return list
+}
+
+// bounds returns the rectangle around the selected control points. Unlike
+// stroke.bounds it contains the control points of curves, not the curves,
+// since those are what the editor's handles move.
+func (sel selection) bounds(shape strokes) bounds {
+	b := emptyBounds
+	for ref := range sel {
+		x, y := shape[ref.stroke].point(ref.point)
+		b = b.add(*x, *y)
+	}
+	return b
+}
+
+// transformed returns a copy of the shape with m applied to the selected
+// points only.
+func (sel selection) transformed(shape strokes, m affine) strokes {
+	out := append(strokes(nil), shape...)
+	for ref := range sel {
+		x, y := out[ref.stroke].point(ref.point)
+		*x, *y = m.apply(*x, *y)
+	}
+	return out
+}
+
+// without returns the shape without the strokes that have selected points.
+func (sel selection) without(shape strokes) strokes {
+	var out strokes
+	removed := make(map[int]bool)
+	for _, i := range sel.strokes() {
+		removed[i] = true
+	}
+	for i := range shape {
+		if !removed[i] {
+			out = append(out, shape[i])
+		}
+	}
+	return out
+}
+
+// distanceToStroke is how far x,y is from the drawn stroke, not counting the
+// pen's width.
+func distanceToStroke(s stroke, x, y float64) float64 {
+	_, _, d := closestOnStroke(s, x, y)
+	return d
+}
+
+// closestOnStroke returns the point on the stroke that is closest to x,y and
+// its distance. For curves it is off by at most a ten thousandth of the
+// canvas.
+func closestOnStroke(s stroke, x, y float64) (cx, cy, distance float64) {
+	return closestOnPolyline(s.flatten(0.0001), x, y)
+}
+
+// closestOnPolyline returns the point on the polyline that is closest to x,y
+// and its distance.
+func closestOnPolyline(points [][2]float64, x, y float64) (cx, cy, distance float64) {
+	cx, cy = points[0][0], points[0][1]
+	distance = math.Hypot(cx-x, cy-y)
+	for i := 1; i < len(points); i++ {
+		a, b := points[i-1], points[i]
+		dx, dy := b[0]-a[0], b[1]-a[1]
+		t := 0.0
+		if length := dx*dx + dy*dy; length > 0 {
+			t = ((x-a[0])*dx + (y-a[1])*dy) / length
+			t = math.Max(0, math.Min(1, t))
+		}
+		px, py := a[0]+t*dx, a[1]+t*dy
+		if d := math.Hypot(px-x, py-y); d < distance {
+			cx, cy, distance = px, py, d
+		}
+	}
+	return cx, cy, distance
+}
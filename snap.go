@@ -0,0 +1,94 @@
+package main
+
+import "math"
+
+// snapKind is what a dragged point snapped to, from strongest to weakest.
+type snapKind int
+
+const (
+	// snapToEnd is the start or end of another stroke, or a dot. Strokes
+	// whose ends are exactly the same are joined when exporting, see
+	// linearize.
+	snapToEnd snapKind = iota
+	// snapToStroke is any point on another stroke, e.g. for a T-junction.
+	snapToStroke
+	// snapToGuide is a horizontal or vertical guide line like the base line.
+	snapToGuide
+)
+
+// snapGuides are the lines that points snap to, the y coordinates of
+// horizontal and the x coordinates of vertical lines.
+type snapGuides struct {
+	xs, ys []float64
+}
+
+// snap is where a point snapped to.
+type snap struct {
+	x, y float64
+	kind snapKind
+	// distance is how far the point had to move to snap.
+	distance float64
+}
+
+// findSnap finds the strongest snap for x,y within radius. Ends of strokes
+// are preferred over points on strokes and those over guides, the closest one
+// of each kind is taken. The strokes in skip are not snapped to, e.g. those
+// being dragged. A point can snap to a horizontal and a vertical guide at the
+// same time.
+func findSnap(shape strokes, skip map[int]bool, guides snapGuides, x, y, radius float64) (snap, bool) {
+	best := snap{distance: math.Inf(1)}
+	found := false
+	try := func(s snap) {
+		if s.distance <= radius &&
+			(!found || s.kind < best.kind || s.kind == best.kind && s.distance < best.distance) {
+			best = s
+			found = true
+		}
+	}
+
+	for i := range shape {
+		if skip[i] {
+			continue
+		}
+		for _, p := range [][2]float64{shape[i].start(), shape[i].end()} {
+			try(snap{x: p[0], y: p[1], kind: snapToEnd, distance: math.Hypot(p[0]-x, p[1]-y)})
+		}
+	}
+	if found {
+		return best, true
+	}
+
+	for i := range shape {
+		if !skip[i] && shape[i].typ != dot {
+			cx, cy, d := closestOnStroke(shape[i], x, y)
+			try(snap{x: cx, y: cy, kind: snapToStroke, distance: d})
+		}
+	}
+	if found {
+		return best, true
+	}
+
+	closest := func(lines []float64, v float64) (float64, bool) {
+		best, ok := 0.0, false
+		for _, l := range lines {
+			if math.Abs(l-v) <= radius && (!ok || math.Abs(l-v) < math.Abs(best-v)) {
+				best, ok = l, true
+			}
+		}
+		return best, ok
+	}
+	gx, okX := closest(guides.xs, x)
+	gy, okY := closest(guides.ys, y)
+	if !okX {
+		gx = x
+	}
+	if !okY {
+		gy = y
+	}
+	if okX || okY {
+		// each coordinate moved by at most the radius
+		d := math.Max(math.Abs(gx-x), math.Abs(gy-y))
+		try(snap{x: gx, y: gy, kind: snapToGuide, distance: d})
+	}
+	return best, found
+}
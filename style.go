@@ -0,0 +1,74 @@
+package main
+
+import "strings"
+
+// styleVariant describes how a style of a font family is derived from its
+// master, e.g. an oblique or a condensed style.
+type styleVariant struct {
+	// name is the new style's name. If it is empty, it is made up from the
+	// parameters, see styleName.
+	name string
+	// shear is the oblique angle in radians, positive leans to the right. The
+	// base line stays in place and the advances do not change.
+	shear float64
+	// scaleX stretches (> 1) or condenses (< 1) each letter around its center.
+	// The side bearings stay the same so the advance changes by as much as
+	// the letter's width. Letters without strokes have their advance scaled.
+	scaleX float64
+	// spacing is added to both side bearings of each letter, i.e. twice to
+	// the advance. It is negative for tighter spacing.
+	spacing float64
+}
+
+// deriveStyle returns the master's letters transformed into the variant, with
+// all advances set, even those the master only estimates.
+func deriveStyle(master letters, v styleVariant) letters {
+	f := newFont(master)
+	out := make(letters, 0, len(master))
+	for _, l := range master {
+		if !hasGlyph(l) {
+			out = append(out, l)
+			continue
+		}
+		advance := f.advance(l.r)
+		b := l.shape.bounds()
+		if b.empty() {
+			l.advance = advance*v.scaleX + 2*v.spacing
+			out = append(out, l)
+			continue
+		}
+		// scaling around the center moves the left side of the letter, it is
+		// moved back to keep the left side bearing
+		m := glyphTransform{scaleX: v.scaleX, scaleY: 1}.matrix(l.shape).
+			then(translation(-(1-v.scaleX)*b.width()/2, 0)).
+			then(shear(v.shear, baseLine)).
+			then(translation(v.spacing, 0))
+		l.shape = l.shape.transformed(m)
+		l.advance = advance + (v.scaleX-1)*b.width() + 2*v.spacing
+		out = append(out, l)
+	}
+	return out
+}
+
+// styleName describes the variant with the usual names for font styles, e.g.
+// "Condensed Oblique". It is "Regular" if the variant only changes the
+// spacing.
+func styleName(v styleVariant) string {
+	if v.name != "" {
+		return v.name
+	}
+	var parts []string
+	if v.scaleX < 1 {
+		parts = append(parts, "Condensed")
+	}
+	if v.scaleX > 1 {
+		parts = append(parts, "Extended")
+	}
+	if v.shear != 0 {
+		parts = append(parts, "Oblique")
+	}
+	if len(parts) == 0 {
+		return "Regular"
+	}
+	return strings.Join(parts, " ")
+}
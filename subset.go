@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// runeSet is a set of characters, e.g. those that a subset of a font keeps.
+type runeSet map[rune]bool
+
+// addText adds all characters in the text, except for control characters
+// like line breaks.
+func (s runeSet) addText(text string) {
+	for _, r := range text {
+		if !unicode.IsControl(r) {
+			s[r] = true
+		}
+	}
+}
+
+// addRanges adds Unicode ranges, given as a comma-separated list of single
+// code points or ranges of them, e.g. "U+0030-U+0039,U+0041,61-7A". The U+
+// is optional, all numbers are hexadecimal.
+func (s runeSet) addRanges(ranges string) error {
+	parse := func(point string) (rune, error) {
+		point = strings.TrimSpace(point)
+		point = strings.TrimPrefix(strings.TrimPrefix(point, "U+"), "u+")
+		n, err := strconv.ParseUint(point, 16, 32)
+		if err != nil || n > unicode.MaxRune {
+			return 0, fmt.Errorf("invalid code point %q", point)
+		}
+		return rune(n), nil
+	}
+	for _, part := range strings.Split(ranges, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		ends := strings.SplitN(part, "-", 2)
+		first, err := parse(ends[0])
+		if err != nil {
+			return err
+		}
+		last := first
+		if len(ends) == 2 {
+			if last, err = parse(ends[1]); err != nil {
+				return err
+			}
+		}
+		if last < first {
+			return fmt.Errorf("invalid range %q", part)
+		}
+		for r := first; r <= last; r++ {
+			s[r] = true
+		}
+	}
+	return nil
+}
+
+// subsetFont returns only the letters whose runes are in the set. The notdef
+// glyph is always kept since the letters that were left out would be drawn
+// with it. The font format has no composite glyphs, so there are no other
+// letters that the kept ones depend on.
+func subsetFont(list letters, keep runeSet) letters {
+	var out letters
+	for _, l := range list {
+		if keep[l.r] || l.r == notdef {
+			out = append(out, l)
+		}
+	}
+	return out
+}
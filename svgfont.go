@@ -0,0 +1,409 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// svgUnitsPerEm is the size of a letter's canvas in SVG font units.
+const svgUnitsPerEm = 1000
+
+type svgFile struct {
+	XMLName xml.Name  `xml:"svg"`
+	Xmlns   string    `xml:"xmlns,attr,omitempty"`
+	Defs    []svgFont `xml:"defs>font"`
+	Fonts   []svgFont `xml:"font"`
+}
+
+type svgFont struct {
+	ID           string      `xml:"id,attr,omitempty"`
+	HorizAdvX    float64     `xml:"horiz-adv-x,attr"`
+	FontFace     svgFontFace `xml:"font-face"`
+	MissingGlyph *svgGlyph   `xml:"missing-glyph"`
+	Glyphs       []svgGlyph  `xml:"glyph"`
+}
+
+type svgFontFace struct {
+	FontFamily string  `xml:"font-family,attr,omitempty"`
+	UnitsPerEm float64 `xml:"units-per-em,attr,omitempty"`
+	Ascent     float64 `xml:"ascent,attr"`
+	Descent    float64 `xml:"descent,attr"`
+}
+
+type svgGlyph struct {
+	Unicode   string  `xml:"unicode,attr,omitempty"`
+	Name      string  `xml:"glyph-name,attr,omitempty"`
+	HorizAdvX float64 `xml:"horiz-adv-x,attr,omitempty"`
+	D         string  `xml:"d,attr,omitempty"`
+}
+
+// exportSVGFont writes the letters as an SVG font like the ones used by the
+// Hershey Text extension for Inkscape. Each letter becomes a glyph whose path
+// is not closed, i.e. it is meant to be stroked, not filled. The canvas is
+// svgUnitsPerEm wide and high with the base line at y = 0 and y pointing up.
+//
+// Dots are written as lines of length 0. The notdef glyph becomes the font's
+// missing-glyph. Glyphs only get a horiz-adv-x if their letter has an
+// advance, otherwise the font's default of one canvas width applies. Letters whose runes cannot be written in XML, like control
+// characters, are left out. Of the font info only the family is kept, it
+// defaults to the file name.
+func exportSVGFont(list letters, info fontInfo, path string) error {
+	list = append(letters(nil), list...)
+	sort.Sort(list)
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	family := info.family
+	if family == "" {
+		family = name
+	}
+	out := svgFont{
+		ID:        name,
+		HorizAdvX: svgUnitsPerEm,
+		FontFace: svgFontFace{
+			FontFamily: family,
+			UnitsPerEm: svgUnitsPerEm,
+			Ascent:     math.Round(baseLine * svgUnitsPerEm),
+			Descent:    math.Round((baseLine - 1) * svgUnitsPerEm),
+		},
+	}
+	for _, l := range list {
+		if !hasGlyph(l) {
+			continue
+		}
+		g := svgGlyph{
+			Unicode:   string(l.r),
+			HorizAdvX: math.Round(l.advance*svgUnitsPerEm*1e9) / 1e9,
+			D:         svgPath(linearize(l.shape)),
+		}
+		if l.r == notdef {
+			g.Unicode = ""
+			out.MissingGlyph = &g
+		} else if isXMLChar(l.r) {
+			out.Glyphs = append(out.Glyphs, g)
+		}
+	}
+
+	data, err := xml.MarshalIndent(svgFile{
+		Xmlns: "http://www.w3.org/2000/svg",
+		Defs:  []svgFont{out},
+	}, "", "\t")
+	if err != nil {
+		return err
+	}
+	data = append([]byte(xml.Header), data...)
+	return ioutil.WriteFile(path, data, 0666)
+}
+
+// svgNumber formats f with enough digits for importSVGFont to get back the
+// same float32 that font files store.
+func svgNumber(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 9, 64)
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
+
+func isXMLChar(r rune) bool {
+	return r == 0x9 || r == 0xA || r == 0xD ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= 0x10FFFF
+}
+
+// svgPath converts the strokes to SVG path data. Strokes that start where the
+// previous one ended continue the current sub-path.
+func svgPath(shape strokes) string {
+	var cmds []string
+	p := func(x, y float64) string {
+		return svgNumber(x*svgUnitsPerEm) + " " +
+			svgNumber((baseLine-y)*svgUnitsPerEm)
+	}
+	for i, s := range shape {
+		if i == 0 || shape[i-1].end() != s.start() {
+			cmds = append(cmds, "M "+p(s.x1, s.y1))
+		}
+		switch s.typ {
+		case dot:
+			cmds = append(cmds, "L "+p(s.x1, s.y1))
+		case line:
+			cmds = append(cmds, "L "+p(s.x2, s.y2))
+		case curve:
+			cmds = append(cmds, "Q "+p(s.x2, s.y2)+" "+p(s.x3, s.y3))
+		default:
+			panic("unknown stroke type")
+		}
+	}
+	return strings.Join(cmds, " ")
+}
+
+// importSVGFont reads the first font in an SVG file. Glyphs for more than one
+// character, i.e. ligatures, are skipped. Path data may contain all commands
+// except for arcs, cubic curves are approximated by quadratic ones. Glyphs
+// without their own horiz-adv-x get no advance, which is how exportSVGFont
+// writes letters without one, so their advance is computed from the strokes.
+func importSVGFont(path string) (letters, fontInfo, error) {
+	var info fontInfo
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, info, err
+	}
+	var file svgFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, info, err
+	}
+	fonts := append(file.Defs, file.Fonts...)
+	if len(fonts) == 0 {
+		return nil, info, errors.New("SVG file contains no font")
+	}
+	f := fonts[0]
+	info.family = f.FontFace.FontFamily
+
+	unitsPerEm := f.FontFace.UnitsPerEm
+	if unitsPerEm == 0 {
+		unitsPerEm = svgUnitsPerEm
+	}
+	// The base line at 2/3 cannot be represented exactly, so moving it to 0
+	// and back leaves tiny errors. Rounding to a grid of 2^-30 removes them
+	// and keeps all float32 values, which is what font files store, that are
+	// not too close to 0.
+	round := func(f float64) float64 {
+		const grid = 1 << 30
+		return float64(float32(math.Round(f*grid) / grid))
+	}
+	toCanvas := func(x, y float64) (float64, float64) {
+		return round(x / unitsPerEm), round(baseLine - y/unitsPerEm)
+	}
+
+	var list letters
+	add := func(r rune, g svgGlyph) error {
+		shape, err := parseSVGPath(g.D, toCanvas)
+		if err != nil {
+			return fmt.Errorf("glyph %s: %v", letterName(r), err)
+		}
+		list = append(list, letter{
+			r:       r,
+			shape:   shape,
+			advance: round(g.HorizAdvX / unitsPerEm),
+		})
+		return nil
+	}
+	if f.MissingGlyph != nil {
+		if err := add(notdef, *f.MissingGlyph); err != nil {
+			return nil, info, err
+		}
+	}
+	for _, g := range f.Glyphs {
+		r, n := utf8.DecodeRuneInString(g.Unicode)
+		if n == 0 || n != len(g.Unicode) {
+			continue
+		}
+		if err := add(r, g); err != nil {
+			return nil, info, err
+		}
+	}
+	return list, info, nil
+}
+
+// parseSVGPath converts SVG path data to strokes, transforming all points with
+// toCanvas. Lines of length 0 become dots.
+func parseSVGPath(
+	d string,
+	toCanvas func(x, y float64) (float64, float64),
+) (strokes, error) {
+	var shape strokes
+	var (
+		x, y           float64 // current point
+		startX, startY float64 // start of the current sub-path
+		ctrlX, ctrlY   float64 // last control point, for T and S
+		cmd, lastCmd   byte
+		subPathStart   int // index in shape where the current sub-path starts
+	)
+
+	addLine := func(toX, toY float64) {
+		x1, y1 := toCanvas(x, y)
+		x2, y2 := toCanvas(toX, toY)
+		if x1 == x2 && y1 == y2 {
+			shape = append(shape, stroke{typ: dot, x1: x1, y1: y1})
+		} else {
+			shape = append(shape, stroke{typ: line, x1: x1, y1: y1, x2: x2, y2: y2})
+		}
+		x, y = toX, toY
+	}
+	addCurve := func(cx, cy, toX, toY float64) {
+		s := stroke{typ: curve}
+		s.x1, s.y1 = toCanvas(x, y)
+		s.x2, s.y2 = toCanvas(cx, cy)
+		s.x3, s.y3 = toCanvas(toX, toY)
+		shape = append(shape, s)
+		x, y = toX, toY
+	}
+	// addCubic approximates the cubic curve by two quadratic ones, one for
+	// each half of the curve.
+	addCubic := func(c1x, c1y, c2x, c2y, toX, toY float64) {
+		mid := func(a, b float64) float64 { return (a + b) / 2 }
+		// de Casteljau split at t = 0.5
+		ax, ay := mid(x, c1x), mid(y, c1y)
+		bx, by := mid(c1x, c2x), mid(c1y, c2y)
+		cx, cy := mid(c2x, toX), mid(c2y, toY)
+		abx, aby := mid(ax, bx), mid(ay, by)
+		bcx, bcy := mid(bx, cx), mid(by, cy)
+		mx, my := mid(abx, bcx), mid(aby, bcy)
+		quad := func(p0x, p0y, c1x, c1y, c2x, c2y, p3x, p3y float64) (float64, float64) {
+			return (3*(c1x+c2x) - p0x - p3x) / 4, (3*(c1y+c2y) - p0y - p3y) / 4
+		}
+		q1x, q1y := quad(x, y, ax, ay, abx, aby, mx, my)
+		q2x, q2y := quad(mx, my, bcx, bcy, cx, cy, toX, toY)
+		addCurve(q1x, q1y, mx, my)
+		addCurve(q2x, q2y, toX, toY)
+	}
+
+	i := 0
+	skipSpace := func() {
+		for i < len(d) && strings.IndexByte(" \t\r\n,", d[i]) != -1 {
+			i++
+		}
+	}
+	isNumberStart := func() bool {
+		skipSpace()
+		return i < len(d) && strings.IndexByte("+-.0123456789", d[i]) != -1
+	}
+	number := func() (float64, error) {
+		if !isNumberStart() {
+			return 0, errors.New("number expected in path data")
+		}
+		start := i
+		if d[i] == '+' || d[i] == '-' {
+			i++
+		}
+		digits := func() {
+			for i < len(d) && '0' <= d[i] && d[i] <= '9' {
+				i++
+			}
+		}
+		digits()
+		if i < len(d) && d[i] == '.' {
+			i++
+			digits()
+		}
+		if i < len(d) && (d[i] == 'e' || d[i] == 'E') {
+			i++
+			if i < len(d) && (d[i] == '+' || d[i] == '-') {
+				i++
+			}
+			digits()
+		}
+		return strconv.ParseFloat(d[start:i], 64)
+	}
+	numbers := func(n []*float64) error {
+		for _, p := range n {
+			f, err := number()
+			if err != nil {
+				return err
+			}
+			*p = f
+		}
+		return nil
+	}
+
+	for {
+		skipSpace()
+		if i >= len(d) {
+			break
+		}
+		if !isNumberStart() {
+			cmd = d[i]
+			i++
+		} else if cmd == 0 {
+			return nil, errors.New("command expected in path data")
+		}
+		// relative commands are lower case
+		rel := 'a' <= cmd && cmd <= 'z'
+		var dx, dy float64
+		if rel {
+			dx, dy = x, y
+		}
+
+		var a, b, c, e, f, g float64
+		var err error
+		switch cmd {
+		case 'M', 'm':
+			if err = numbers([]*float64{&a, &b}); err == nil {
+				x, y = a+dx, b+dy
+				startX, startY = x, y
+				subPathStart = len(shape)
+				// further coordinate pairs are implicit line commands
+				if rel {
+					cmd = 'l'
+				} else {
+					cmd = 'L'
+				}
+			}
+		case 'L', 'l':
+			if err = numbers([]*float64{&a, &b}); err == nil {
+				addLine(a+dx, b+dy)
+			}
+		case 'H', 'h':
+			if err = numbers([]*float64{&a}); err == nil {
+				addLine(a+dx, y)
+			}
+		case 'V', 'v':
+			if err = numbers([]*float64{&a}); err == nil {
+				addLine(x, a+dy)
+			}
+		case 'Q', 'q':
+			if err = numbers([]*float64{&a, &b, &c, &e}); err == nil {
+				ctrlX, ctrlY = a+dx, b+dy
+				addCurve(ctrlX, ctrlY, c+dx, e+dy)
+			}
+		case 'T', 't':
+			if err = numbers([]*float64{&a, &b}); err == nil {
+				if strings.IndexByte("QqTt", lastCmd) != -1 {
+					ctrlX, ctrlY = 2*x-ctrlX, 2*y-ctrlY
+				} else {
+					ctrlX, ctrlY = x, y
+				}
+				addCurve(ctrlX, ctrlY, a+dx, b+dy)
+			}
+		case 'C', 'c':
+			if err = numbers([]*float64{&a, &b, &c, &e, &f, &g}); err == nil {
+				ctrlX, ctrlY = c+dx, e+dy
+				addCubic(a+dx, b+dy, ctrlX, ctrlY, f+dx, g+dy)
+			}
+		case 'S', 's':
+			if err = numbers([]*float64{&c, &e, &f, &g}); err == nil {
+				if strings.IndexByte("CcSs", lastCmd) != -1 {
+					a, b = 2*x-ctrlX, 2*y-ctrlY
+				} else {
+					a, b = x, y
+				}
+				ctrlX, ctrlY = c+dx, e+dy
+				addCubic(a, b, ctrlX, ctrlY, f+dx, g+dy)
+			}
+		case 'Z', 'z':
+			if x != startX || y != startY || len(shape) == subPathStart {
+				addLine(startX, startY)
+			}
+			subPathStart = len(shape)
+			// Z has no arguments so it cannot be repeated implicitly
+			cmd = 0
+		default:
+			return nil, fmt.Errorf("unsupported path command %q", cmd)
+		}
+		if err != nil {
+			return nil, err
+		}
+		lastCmd = cmd
+	}
+	return shape, nil
+}
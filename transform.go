@@ -0,0 +1,49 @@
+package main
+
+// glyphTransform is a transform of whole letters. Its parts are applied in the
+// order of the fields, all around a pivot on the base line: the center of each
+// letter's strokes or the left end of the base line.
+type glyphTransform struct {
+	scaleX, scaleY float64
+	// shear is the oblique angle in radians, positive leans to the right.
+	shear float64
+	// rotate is the angle in radians, positive turns clockwise.
+	rotate           float64
+	mirrorX, mirrorY bool
+	dx, dy           float64
+	// aroundOrigin uses the left end of the base line as the pivot for all
+	// letters, instead of each letter's center.
+	aroundOrigin bool
+}
+
+// matrix returns the transform for the given strokes.
+func (t glyphTransform) matrix(shape strokes) affine {
+	x := 0.0
+	if b := shape.bounds(); !t.aroundOrigin && !b.empty() {
+		x = (b.minX + b.maxX) / 2
+	}
+	m := scaling(t.scaleX, t.scaleY).
+		then(shear(t.shear, 0)).
+		then(rotation(t.rotate))
+	if t.mirrorX {
+		m = m.then(mirrorX(0))
+	}
+	if t.mirrorY {
+		m = m.then(mirrorY(0))
+	}
+	return m.around(x, baseLine).then(translation(t.dx, t.dy))
+}
+
+// transformLetters returns a copy of the list with the letters in only
+// transformed, or all of them if only is nil. Advances are kept as they are,
+// autoMetrics can set new ones.
+func transformLetters(list letters, t glyphTransform, only map[rune]bool) letters {
+	out := make(letters, len(list))
+	for i, l := range list {
+		if only == nil || only[l.r] {
+			l.shape = l.shape.transformed(t.matrix(l.shape))
+		}
+		out[i] = l
+	}
+	return out
+}
@@ -0,0 +1,66 @@
+package main
+
+// letterState is a version of a letter in the editor's undo history.
+type letterState struct {
+	shape   strokes
+	advance float64
+}
+
+func (s letterState) copy() letterState {
+	s.shape = append(strokes(nil), s.shape...)
+	return s
+}
+
+func (s letterState) equals(t letterState) bool {
+	if len(s.shape) != len(t.shape) || s.advance != t.advance {
+		return false
+	}
+	for i := range s.shape {
+		if s.shape[i] != t.shape[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// maxUndos is how many versions of each letter are kept for undoing.
+const maxUndos = 100
+
+// editHistory keeps the earlier versions of a letter for undoing changes, and
+// the undone versions for redoing them.
+type editHistory struct {
+	undos []letterState
+	redos []letterState
+}
+
+// remember is called with the letter as it is before a change. Anything that
+// was undone cannot be redone after a new change.
+func (h *editHistory) remember(s letterState) {
+	h.undos = append(h.undos, s.copy())
+	if len(h.undos) > maxUndos {
+		h.undos = h.undos[len(h.undos)-maxUndos:]
+	}
+	h.redos = nil
+}
+
+// undo returns the version before cur, false if there is none.
+func (h *editHistory) undo(cur letterState) (letterState, bool) {
+	if len(h.undos) == 0 {
+		return cur, false
+	}
+	s := h.undos[len(h.undos)-1]
+	h.undos = h.undos[:len(h.undos)-1]
+	h.redos = append(h.redos, cur.copy())
+	return s, true
+}
+
+// redo returns the version that cur was undone from, false if there is none.
+func (h *editHistory) redo(cur letterState) (letterState, bool) {
+	if len(h.redos) == 0 {
+		return cur, false
+	}
+	s := h.redos[len(h.redos)-1]
+	h.redos = h.redos[:len(h.redos)-1]
+	h.undos = append(h.undos, cur.copy())
+	return s, true
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// fontProblem is something wrong with a font that validateFont found.
+type fontProblem struct {
+	r rune
+	// stroke is the index of the stroke in the letter, -1 if the problem is
+	// about the whole letter.
+	stroke int
+	// fatal problems break exporters or renderers, the others are only
+	// suspicious and probably not what the font's author wanted.
+	fatal   bool
+	message string
+}
+
+func (p fontProblem) String() string {
+	kind := "warning"
+	if p.fatal {
+		kind = "error"
+	}
+	name := letterName(p.r)
+	if p.r != notdef {
+		name += fmt.Sprintf(" U+%04X", p.r)
+	}
+	if p.stroke < 0 {
+		return fmt.Sprintf("%s: %s: %s", kind, name, p.message)
+	}
+	return fmt.Sprintf("%s: %s stroke %d: %s", kind, name, p.stroke, p.message)
+}
+
+// validateFont checks the letters for problems, sorted by rune and stroke.
+func validateFont(list letters) []fontProblem {
+	var problems []fontProblem
+	report := func(r rune, stroke int, fatal bool, format string, a ...interface{}) {
+		problems = append(problems, fontProblem{
+			r:       r,
+			stroke:  stroke,
+			fatal:   fatal,
+			message: fmt.Sprintf(format, a...),
+		})
+	}
+
+	seen := make(map[rune]bool)
+	for _, l := range list {
+		if seen[l.r] {
+			report(l.r, -1, true, "letter is defined more than once")
+		}
+		seen[l.r] = true
+
+		if l.r != notdef && !utf8.ValidRune(l.r) {
+			report(l.r, -1, true, "not a valid Unicode character")
+		}
+		if math.IsNaN(l.advance) || math.IsInf(l.advance, 0) {
+			report(l.r, -1, true, "advance is not a number")
+		} else if l.advance < 0 {
+			report(l.r, -1, true, "advance is negative")
+		}
+		if len(l.shape) == 0 && l.advance != 0 &&
+			!unicode.IsSpace(l.r) && l.r != notdef {
+			report(l.r, -1, false, "letter has an advance but no strokes")
+		}
+
+		for i, s := range l.shape {
+			points := [][2]float64{{s.x1, s.y1}}
+			if s.typ != dot {
+				points = append(points, [2]float64{s.x2, s.y2})
+			}
+			if s.typ == curve {
+				points = append(points, [2]float64{s.x3, s.y3})
+			}
+			valid := true
+			for _, p := range points {
+				for _, c := range p {
+					if math.IsNaN(c) || math.IsInf(c, 0) {
+						valid = false
+					}
+				}
+			}
+			if !valid {
+				report(l.r, i, true, "coordinate is not a number")
+				continue
+			}
+			b := s.bounds()
+			if b.minX < 0 || b.minY < 0 || b.maxX > 1 || b.maxY > 1 {
+				report(l.r, i, false, "stroke leaves the canvas")
+			}
+			switch s.typ {
+			case dot:
+			case line:
+				if points[0] == points[1] {
+					report(l.r, i, false, "line has length 0, it is drawn as a dot")
+				}
+			case curve:
+				a, c, e := points[0], points[1], points[2]
+				cross := (c[0]-a[0])*(e[1]-a[1]) - (c[1]-a[1])*(e[0]-a[0])
+				if c == e {
+					report(l.r, i, false, "curve's control point is its end, it is drawn as a line")
+				} else if cross == 0 {
+					report(l.r, i, false, "curve is straight, it could be a line")
+				}
+			default:
+				panic("unknown stroke type")
+			}
+			for j := 0; j < i; j++ {
+				if sameStroke(l.shape[j], s) {
+					report(l.r, i, false, "stroke is the same as stroke %d", j)
+					break
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].r < problems[j].r
+	})
+	return problems
+}
+
+// sameStroke tells if a and b draw the same, possibly in opposite directions.
+func sameStroke(a, b stroke) bool {
+	if a.typ != b.typ {
+		return false
+	}
+	if a.start() == b.start() && a.end() == b.end() ||
+		a.start() == b.end() && a.end() == b.start() {
+		return a.typ != curve || a.x2 == b.x2 && a.y2 == b.y2
+	}
+	return false
+}